@@ -0,0 +1,96 @@
+/*
+Package alogr provides a logr.LogSink implementation backed by an alog.Logger.
+
+This allows libraries that log through github.com/go-logr/logr, such as
+Kubernetes client libraries and controller-runtime, to write their output
+through the same asynchronous alog instance as the rest of an application.
+
+logr V-levels are mapped to alog levels: V(0) logs at alog.InfoLevel, and any
+greater verbosity logs at alog.DebugLevel.  Key/value pairs become alog fields,
+and names given to WithName are passed to alog's Logger.Named, so they are
+joined with "." into the NameField field and select the level set for the name.
+*/
+package alogr
+
+import (
+	"fmt"
+
+	"github.com/gammazero/alog"
+	"github.com/go-logr/logr"
+)
+
+// NameField is the field that holds the hierarchical logger name built by
+// logr.Logger.WithName.
+const NameField = alog.NameField
+
+// missingValue is the value given to a key that has no value.
+const missingValue = "(MISSING)"
+
+type sink struct {
+	lg alog.Logger
+}
+
+// New creates a logr.Logger that writes to the given alog.Logger.
+func New(lg alog.Logger) logr.Logger {
+	return logr.New(NewSink(lg))
+}
+
+// NewSink creates a logr.LogSink that writes to the given alog.Logger.
+func NewSink(lg alog.Logger) logr.LogSink {
+	return &sink{lg: lg}
+}
+
+func (s *sink) Init(info logr.RuntimeInfo) {}
+
+func (s *sink) Enabled(level int) bool {
+	return s.lg.Enabled(alogLevel(level))
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	lg := s.withValues(keysAndValues)
+	if alogLevel(level) == alog.InfoLevel {
+		lg.Info(msg)
+	} else {
+		lg.Debug(msg)
+	}
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.withValues(keysAndValues).WithError(err).Error(msg)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &sink{lg: s.withValues(keysAndValues)}
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	return &sink{lg: s.lg.Named(name)}
+}
+
+// withValues returns a Logger that has the key/value pairs as fields.
+func (s *sink) withValues(keysAndValues []interface{}) alog.Logger {
+	if len(keysAndValues) == 0 {
+		return s.lg
+	}
+	fields := make(alog.Fields, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		if i+1 < len(keysAndValues) {
+			fields[key] = keysAndValues[i+1]
+		} else {
+			fields[key] = missingValue
+		}
+	}
+	return s.lg.WithFields(fields)
+}
+
+// alogLevel converts a logr V-level to an alog level.
+func alogLevel(level int) alog.Level {
+	if level <= 0 {
+		return alog.InfoLevel
+	}
+	return alog.DebugLevel
+}
@@ -0,0 +1,49 @@
+package alogr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gammazero/alog"
+)
+
+func TestLogr(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := alog.NewJSON(buf, alog.InfoLevel, " ")
+
+	log := New(lg).WithName("operator").WithValues("ns", "default")
+	log.WithName("reconciler").Info("reconciling", "pod", "web-1")
+	log.V(1).Info("too verbose")
+	log.Error(errors.New("boom"), "failed", "attempt", 3, "dangling")
+	if log.V(1).Enabled() {
+		t.Error("V(1) should not be enabled at InfoLevel")
+	}
+	lg.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), lines)
+	}
+
+	var ent map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &ent); err != nil {
+		t.Fatal(err)
+	}
+	if ent[NameField] != "operator.reconciler" || ent["ns"] != "default" ||
+		ent["pod"] != "web-1" || ent["level"] != "info" {
+		t.Error("bad info entry:", lines[0])
+	}
+
+	ent = nil
+	if err := json.Unmarshal([]byte(lines[1]), &ent); err != nil {
+		t.Fatal(err)
+	}
+	if ent[NameField] != "operator" || ent[alog.ErrorField] != "boom" ||
+		ent["attempt"] != 3.0 || ent["dangling"] != missingValue ||
+		ent["level"] != "error" || ent["msg"] != "failed" {
+		t.Error("bad error entry:", lines[1])
+	}
+}
@@ -0,0 +1,237 @@
+package alog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ContextExtractor returns fields to log from the values of a context.  It
+// may return nil if the context has none of the values it looks for.
+type ContextExtractor func(ctx context.Context) Fields
+
+// ContextHook is called with the context and the entry of each entry logged
+// by a context-aware method, such as InfoContext.
+type ContextHook func(ctx context.Context, e *Entry)
+
+// The registered functions are stored by pointer, so that the function
+// returned by a Register function can find its registration to remove it.
+var (
+	contextMutex sync.Mutex
+	extractors   atomic.Value // []*ContextExtractor
+	hooks        atomic.Value // []*ContextHook
+)
+
+// RegisterContextExtractor adds a function that the context-aware log methods,
+// such as InfoContext, call to get fields from the context to log with the
+// entry.  Extractors are called in the order they are registered, by the
+// goroutine that logs the entry, and only if the entry's level is enabled.
+// Fields returned by later extractors replace those of earlier ones, and
+// fields given to the Logger by WithFields replace those of all extractors.
+//
+// RegisterContextExtractor returns a function that removes the extractor.
+func RegisterContextExtractor(fn ContextExtractor) func() {
+	contextMutex.Lock()
+	defer contextMutex.Unlock()
+	reg := &fn
+	old, _ := extractors.Load().([]*ContextExtractor)
+	fns := make([]*ContextExtractor, len(old), len(old)+1)
+	copy(fns, old)
+	extractors.Store(append(fns, reg))
+	return func() {
+		contextMutex.Lock()
+		defer contextMutex.Unlock()
+		old, _ := extractors.Load().([]*ContextExtractor)
+		fns := make([]*ContextExtractor, 0, len(old))
+		for _, r := range old {
+			if r != reg {
+				fns = append(fns, r)
+			}
+		}
+		extractors.Store(fns)
+	}
+}
+
+// RegisterContextHook adds a function that is called with each entry logged
+// by a context-aware method, such as InfoContext, and the context it was
+// logged with.  Hooks are called in the order they are registered, by the
+// goroutine that logs the entry, after the entry passes the Logger's level
+// and any sampling or rate limiting, but before the Logger's Filter.  When
+// hooks are registered, the message and LazyValue fields of these entries are
+// computed by the goroutine that logs the entry, instead of by the goroutine
+// that writes it.
+// A hook must not modify the Entry or retain it after returning.
+//
+// RegisterContextHook returns a function that removes the hook.
+func RegisterContextHook(fn ContextHook) func() {
+	contextMutex.Lock()
+	defer contextMutex.Unlock()
+	reg := &fn
+	old, _ := hooks.Load().([]*ContextHook)
+	fns := make([]*ContextHook, len(old), len(old)+1)
+	copy(fns, old)
+	hooks.Store(append(fns, reg))
+	return func() {
+		contextMutex.Lock()
+		defer contextMutex.Unlock()
+		old, _ := hooks.Load().([]*ContextHook)
+		fns := make([]*ContextHook, 0, len(old))
+		for _, r := range old {
+			if r != reg {
+				fns = append(fns, r)
+			}
+		}
+		hooks.Store(fns)
+	}
+}
+
+// resetContext removes all registered extractors and hooks.
+func resetContext() {
+	contextMutex.Lock()
+	defer contextMutex.Unlock()
+	extractors.Store([]*ContextExtractor(nil))
+	hooks.Store([]*ContextHook(nil))
+}
+
+// runContextHooks calls the registered hooks with the entry.  The entry's
+// message is formatted and its LazyValue fields are computed, and the entry is
+// changed to log the formatted message and computed values, so that the hooks
+// and the sinks see the same entry.
+func runContextHooks(ctx context.Context, ent *entry) {
+	fns, _ := hooks.Load().([]*ContextHook)
+	if len(fns) == 0 {
+		return
+	}
+	msg := ent.message()
+	ent.format = ""
+	ent.args = []interface{}{msg}
+	ent.ln = false
+	ent.fields = resolveFields(ent.fields)
+	e := &Entry{
+		Time:    ent.ts,
+		Level:   ent.level,
+		Message: msg,
+		Fields:  ent.fields,
+	}
+	for _, fn := range fns {
+		(*fn)(ctx, e)
+	}
+}
+
+// ContextValue returns a ContextExtractor that logs the context's value for
+// key, if it has one, as the given field.
+func ContextValue(key interface{}, field string) ContextExtractor {
+	return func(ctx context.Context) Fields {
+		if v := ctx.Value(key); v != nil {
+			return Fields{field: v}
+		}
+		return nil
+	}
+}
+
+// DeadlineRemaining returns a ContextExtractor that logs the time remaining
+// until the context's deadline, if it has one, as the given field.
+func DeadlineRemaining(field string) ContextExtractor {
+	return func(ctx context.Context) Fields {
+		if deadline, ok := ctx.Deadline(); ok {
+			return Fields{field: time.Until(deadline)}
+		}
+		return nil
+	}
+}
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx that carries the Logger.
+func NewContext(ctx context.Context, lg Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, lg)
+}
+
+// FromContext returns the Logger carried by ctx, or nil if ctx has none.
+func FromContext(ctx context.Context) Logger {
+	lg, _ := ctx.Value(loggerKey{}).(Logger)
+	return lg
+}
+
+// withContext returns a fieldLogger that has the fields extracted from ctx,
+// followed by the fieldLogger's own fields, and that gives ctx to the hooks
+// registered by RegisterContextHook.  If entries at the level are not logged,
+// the extractors are not called and the fieldLogger is returned.
+func (f *fieldLogger) withContext(ctx context.Context, level Level) *fieldLogger {
+	fns, _ := extractors.Load().([]*ContextExtractor)
+	hookFns, _ := hooks.Load().([]*ContextHook)
+	if (len(fns) == 0 && len(hookFns) == 0) || f.off ||
+		(!f.LogableAt(level) && f.debugSites == nil) {
+		return f
+	}
+	fields := f.fields
+	var extracted Fields
+	for _, fn := range fns {
+		for k, v := range (*fn)(ctx) {
+			if extracted == nil {
+				extracted = make(Fields, len(f.fields)+1)
+			}
+			extracted[k] = v
+		}
+	}
+	if extracted != nil {
+		for k, v := range f.fields {
+			extracted[k] = v
+		}
+		fields = extracted
+	}
+	if len(hookFns) == 0 {
+		ctx = nil
+		if extracted == nil {
+			return f
+		}
+	}
+	return &fieldLogger{
+		logger: f.logger,
+		fields: fields,
+		gate:   f.gate,
+		name:   f.name,
+		ctx:    ctx,
+	}
+}
+
+func (a *logger) ErrorContext(ctx context.Context, v ...interface{}) {
+	a.root.withContext(ctx, ErrorLevel).emit(ErrorLevel, "", v, false)
+}
+
+func (a *logger) WarnContext(ctx context.Context, v ...interface{}) {
+	a.root.withContext(ctx, WarnLevel).emit(WarnLevel, "", v, false)
+}
+
+func (a *logger) InfoContext(ctx context.Context, v ...interface{}) {
+	a.root.withContext(ctx, InfoLevel).emit(InfoLevel, "", v, false)
+}
+
+func (a *logger) DebugContext(ctx context.Context, v ...interface{}) {
+	a.root.withContext(ctx, DebugLevel).emit(DebugLevel, "", v, false)
+}
+
+func (a *logger) TraceContext(ctx context.Context, v ...interface{}) {
+	a.root.withContext(ctx, TraceLevel).emit(TraceLevel, "", v, false)
+}
+
+func (f *fieldLogger) ErrorContext(ctx context.Context, v ...interface{}) {
+	f.withContext(ctx, ErrorLevel).emit(ErrorLevel, "", v, false)
+}
+
+func (f *fieldLogger) WarnContext(ctx context.Context, v ...interface{}) {
+	f.withContext(ctx, WarnLevel).emit(WarnLevel, "", v, false)
+}
+
+func (f *fieldLogger) InfoContext(ctx context.Context, v ...interface{}) {
+	f.withContext(ctx, InfoLevel).emit(InfoLevel, "", v, false)
+}
+
+func (f *fieldLogger) DebugContext(ctx context.Context, v ...interface{}) {
+	f.withContext(ctx, DebugLevel).emit(DebugLevel, "", v, false)
+}
+
+func (f *fieldLogger) TraceContext(ctx context.Context, v ...interface{}) {
+	f.withContext(ctx, TraceLevel).emit(TraceLevel, "", v, false)
+}
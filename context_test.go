@@ -0,0 +1,81 @@
+package alog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type requestIDKey struct{}
+
+func TestContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+
+	ctx := NewContext(context.Background(), lg)
+	if FromContext(ctx) != lg {
+		t.Fatal("wrong logger from context")
+	}
+	if FromContext(context.Background()) != nil {
+		t.Fatal("expected no logger from empty context")
+	}
+
+	t.Cleanup(resetContext)
+	RegisterContextExtractor(ContextValue(requestIDKey{}, "request_id"))
+	RegisterContextExtractor(DeadlineRemaining("deadline"))
+
+	ctx = context.WithValue(ctx, requestIDKey{}, "r-42")
+	FromContext(ctx).InfoContext(ctx, "handling")
+	lg.WithField("request_id", "own").WarnContext(ctx, "own field wins")
+	lg.DebugContext(ctx, "hidden")
+
+	dctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	lg.ErrorContext(dctx, "with deadline")
+	lg.InfoContext(context.Background(), "no values")
+	lg.Close()
+
+	expect := []string{
+		" INFO handling (request_id=r-42)",
+		" WARN own field wins (request_id=own)",
+		" ERROR with deadline (deadline=59m",
+		" INFO no values",
+	}
+	checkLines(t, buf, expect...)
+}
+
+func TestUnregisterContext(t *testing.T) {
+	t.Cleanup(resetContext)
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+
+	var hooked int
+	unregisterID := RegisterContextExtractor(ContextValue(requestIDKey{}, "request_id"))
+	unregisterTag := RegisterContextExtractor(func(ctx context.Context) Fields {
+		return Fields{"tag": "x"}
+	})
+	unregisterHook := RegisterContextHook(func(ctx context.Context, e *Entry) {
+		hooked++
+	})
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "r-1")
+	lg.InfoContext(ctx, "first")
+	unregisterID()
+	unregisterHook()
+	// Unregistering again has no effect.
+	unregisterID()
+	lg.InfoContext(ctx, "second")
+	unregisterTag()
+	lg.InfoContext(ctx, "third")
+	lg.Close()
+
+	lines := checkLines(t, buf, " INFO first", " INFO second (tag=x)", " INFO third")
+	if !strings.Contains(lines[0], "(request_id=r-1)") || !strings.Contains(lines[0], "(tag=x)") {
+		t.Error("missing fields in first line:", lines[0])
+	}
+	if hooked != 1 {
+		t.Error("expected hook to be called once, got", hooked)
+	}
+}
@@ -0,0 +1,105 @@
+package alog
+
+import (
+	"fmt"
+	"time"
+)
+
+// Dedup returns an Option that collapses consecutive identical entries, having
+// the same level, message, and fields, that are logged within window of the
+// first one.  Only the first entry is written, and when a different entry is
+// logged or the window ends, an entry with the message "last message repeated
+// N times" is written in place of the others, as syslogd does.
+//
+// Deduplication is done by the goroutine that writes log entries, after the
+// Logger's Filter is applied.
+func Dedup(window time.Duration) Option {
+	return func(a *logger) {
+		a.dedupWindow = window
+	}
+}
+
+// deduper counts the repeats of the last entry written.
+type deduper struct {
+	window  time.Duration
+	timer   *time.Timer
+	prev    *Entry
+	last    time.Time
+	repeats int
+}
+
+func newDeduper(window time.Duration) *deduper {
+	d := &deduper{
+		window: window,
+		timer:  time.NewTimer(window),
+	}
+	d.timer.Stop()
+	return d
+}
+
+// repeat reports whether the entry repeats the previous entry within the
+// window, and counts it if so.
+func (d *deduper) repeat(e *Entry) bool {
+	p := d.prev
+	if p == nil || e.Time.Sub(p.Time) >= d.window || e.Level != p.Level ||
+		e.Message != p.Message || !sameFields(e.Fields, p.Fields) {
+		return false
+	}
+	d.repeats++
+	d.last = e.Time
+	return true
+}
+
+// reset makes the entry the one that following entries are compared to, and
+// returns the summary of the previous entry's repeats, if there were any.
+func (d *deduper) reset(e *Entry) *Entry {
+	summary := d.summary()
+	d.prev = e
+	d.repeats = 0
+	if !d.timer.Stop() {
+		select {
+		case <-d.timer.C:
+		default:
+		}
+	}
+	if e != nil {
+		d.timer.Reset(d.window)
+	}
+	return summary
+}
+
+func (d *deduper) summary() *Entry {
+	if d.repeats == 0 {
+		return nil
+	}
+	// The summary has the fields of the repeated entry, so that it is routed
+	// and filtered the same way by sinks.
+	return &Entry{
+		Time:    d.last,
+		Level:   d.prev.Level,
+		Message: fmt.Sprintf("last message repeated %d times", d.repeats),
+		Fields:  d.prev.Fields,
+	}
+}
+
+// expired is called when the timer fires, to end the window of the previous
+// entry and return the summary of its repeats.
+func (d *deduper) expired() *Entry {
+	summary := d.summary()
+	d.prev = nil
+	d.repeats = 0
+	return summary
+}
+
+func sameFields(a, b Fields) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		w, ok := b[k]
+		if !ok || fmt.Sprint(v) != fmt.Sprint(w) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package alog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, DebugLevel, " ", "", Dedup(time.Hour))
+
+	for i := 0; i < 38; i++ {
+		lg.Warn("disk full")
+	}
+	lg.WithField("disk", "sda").Warn("disk full")
+	lg.WithField("disk", "sda").Warn("disk full")
+	lg.Info("recovered")
+	lg.Info("recovered")
+	lg.Info("done")
+	lg.Close()
+
+	expect := []string{
+		" WARN disk full",
+		" WARN last message repeated 37 times",
+		" WARN disk full (disk=sda)",
+		" WARN last message repeated 1 times (disk=sda)",
+		" INFO recovered",
+		" INFO last message repeated 1 times",
+		" INFO done",
+	}
+	checkLines(t, buf, expect...)
+}
+
+// lockedBuffer is a bytes.Buffer that can be read while a Logger writes to it.
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func TestDedupWindow(t *testing.T) {
+	buf := new(lockedBuffer)
+	lg := NewText(buf, DebugLevel, " ", "", Dedup(20*time.Millisecond))
+
+	lg.Info("tick")
+	lg.Info("tick")
+	time.Sleep(100 * time.Millisecond)
+	if !strings.Contains(buf.String(), "last message repeated 1 times") {
+		lg.Close()
+		t.Fatal("expected repeats to be written when window ended")
+	}
+	lg.Info("tick")
+	lg.Close()
+
+	if n := strings.Count(buf.String(), "INFO tick"); n != 2 {
+		t.Errorf("expected entry to be written again after window, got %d: %q", n, buf.String())
+	}
+}
+
+func TestDedupFlush(t *testing.T) {
+	buf := new(lockedBuffer)
+	lg := NewText(buf, DebugLevel, " ", "", Dedup(time.Hour))
+
+	lg.Info("tick")
+	lg.Info("tick")
+	lg.Info("tick")
+	lg.Flush()
+	if s := buf.String(); s != " INFO tick\n INFO last message repeated 2 times\n" {
+		lg.Close()
+		t.Fatalf("expected repeats to be written by Flush, got %q", s)
+	}
+	lg.Close()
+}
@@ -0,0 +1,269 @@
+package alog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DebugSetting controls whether the entries from a debug callsite are logged.
+type DebugSetting int32
+
+const (
+	// DebugDefault logs entries from the callsite if the Logger is enabled at
+	// DebugLevel.
+	DebugDefault DebugSetting = iota
+	// DebugOn logs entries from the callsite regardless of the Logger's level.
+	DebugOn
+	// DebugOff does not log entries from the callsite.
+	DebugOff
+)
+
+var debugSettingNames = [...]string{"default", "on", "off"}
+
+// String returns the name of the setting: "default", "on", or "off".
+func (s DebugSetting) String() string {
+	if s < 0 || int(s) >= len(debugSettingNames) {
+		return "DebugSetting(" + strconv.Itoa(int(s)) + ")"
+	}
+	return debugSettingNames[s]
+}
+
+// ParseDebugSetting returns the DebugSetting that has the given name.
+func ParseDebugSetting(name string) (DebugSetting, error) {
+	for i, n := range debugSettingNames {
+		if strings.EqualFold(n, name) {
+			return DebugSetting(i), nil
+		}
+	}
+	return DebugDefault, fmt.Errorf("unknown debug setting %q", name)
+}
+
+// DebugSite describes a callsite of a Debug method.
+type DebugSite struct {
+	File     string       `json:"file"`
+	Line     int          `json:"line"`
+	Function string       `json:"function"`
+	Format   string       `json:"format"`
+	Setting  DebugSetting `json:"-"`
+}
+
+// MarshalJSON encodes the site with its setting by name.
+func (s DebugSite) MarshalJSON() ([]byte, error) {
+	type site DebugSite
+	return json.Marshal(struct {
+		site
+		Setting string `json:"setting"`
+	}{site(s), s.Setting.String()})
+}
+
+type debugSite struct {
+	DebugSite
+	setting int32
+}
+
+type debugRule struct {
+	pattern string
+	setting DebugSetting
+}
+
+// DebugSites records the callsites of the Debug, Debugln, Debugf, and DebugFn
+// methods of the Loggers it is given to by the DynamicDebug option, and
+// allows logging at each callsite to be switched on or off at runtime,
+// independent of the Logger's level.  This is in the manner of Linux
+// dynamic_debug, except that a callsite is only recorded when it is first
+// called.
+//
+// DebugSites is an http.Handler.  A GET request returns the recorded sites as
+// a JSON array.  A POST request, with the form values "pattern" and
+// "setting", calls Set and returns the number of sites matched.
+type DebugSites struct {
+	sites sync.Map
+	mutex sync.Mutex
+	rules []debugRule
+}
+
+// NewDebugSites creates an empty DebugSites.
+func NewDebugSites() *DebugSites {
+	return &DebugSites{}
+}
+
+// DynamicDebug returns an Option that records the Logger's debug callsites in
+// sites.  Entries from callsites switched on are logged even if the Logger is
+// not enabled at DebugLevel.  With NewMulti, they are still only written to
+// the sinks whose level includes DebugLevel.
+//
+// Finding the callsite takes a call to runtime.Callers for each call to a
+// Debug method, even those that are not logged.
+func DynamicDebug(sites *DebugSites) Option {
+	return func(a *logger) {
+		a.debugSites = sites
+	}
+}
+
+// Sites returns the recorded callsites, sorted by file and line.
+func (ds *DebugSites) Sites() []DebugSite {
+	var sites []DebugSite
+	ds.sites.Range(func(_, v interface{}) bool {
+		s := v.(*debugSite)
+		site := s.DebugSite
+		site.Setting = DebugSetting(atomic.LoadInt32(&s.setting))
+		sites = append(sites, site)
+		return true
+	})
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].File != sites[j].File {
+			return sites[i].File < sites[j].File
+		}
+		return sites[i].Line < sites[j].Line
+	})
+	return sites
+}
+
+// Set applies the setting to the callsites that match pattern, and returns the
+// number of recorded callsites that match.  The setting also applies to
+// matching callsites that are recorded later, unless they match a pattern
+// given to a later call to Set.
+//
+// The pattern uses the syntax of path.Match, and is matched against a site's
+// function name, with or without its package path, and against the trailing
+// components of the site's file path, with or without ":" and the line
+// number.  For example, "db.go", "db.go:42", "store/db.go", "db.*", and
+// "(*Pool).Get" all match a site on line 42 of store/db.go in the method Get
+// of type Pool in package db.
+func (ds *DebugSites) Set(pattern string, setting DebugSetting) (int, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	if setting < DebugDefault || setting > DebugOff {
+		return 0, fmt.Errorf("invalid debug setting %d", int(setting))
+	}
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	ds.rules = append(ds.rules, debugRule{pattern: pattern, setting: setting})
+	var n int
+	ds.sites.Range(func(_, v interface{}) bool {
+		s := v.(*debugSite)
+		if s.match(pattern) {
+			atomic.StoreInt32(&s.setting, int32(setting))
+			n++
+		}
+		return true
+	})
+	return n, nil
+}
+
+// Reset sets all recorded callsites back to DebugDefault, and discards the
+// patterns given to Set.
+func (ds *DebugSites) Reset() {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	ds.rules = nil
+	ds.sites.Range(func(_, v interface{}) bool {
+		atomic.StoreInt32(&v.(*debugSite).setting, int32(DebugDefault))
+		return true
+	})
+}
+
+func (ds *DebugSites) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Content-Type", "application/json")
+		sites := ds.Sites()
+		if sites == nil {
+			sites = []DebugSite{}
+		}
+		json.NewEncoder(w).Encode(sites)
+	case http.MethodPost:
+		setting, err := ParseDebugSetting(r.FormValue("setting"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n, err := ds.Set(r.FormValue("pattern"), setting)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, n)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// enabled reports whether to log an entry from the calling Debug method,
+// where logable is whether the Logger is enabled at DebugLevel.  The site is
+// recorded if this is its first call.  It must be called directly by emit or
+// emitFn.
+func (ds *DebugSites) enabled(logable bool, format string, v []interface{}) bool {
+	var pcs [1]uintptr
+	// Skip runtime.Callers, enabled, emit or emitFn, and the Debug method.
+	if runtime.Callers(4, pcs[:]) == 0 {
+		return logable
+	}
+	s, ok := ds.sites.Load(pcs[0])
+	if !ok {
+		s = ds.record(pcs[0], format, v)
+	}
+	switch DebugSetting(atomic.LoadInt32(&s.(*debugSite).setting)) {
+	case DebugOn:
+		return true
+	case DebugOff:
+		return false
+	}
+	return logable
+}
+
+// record records a new callsite, with the setting of the last rule that
+// matches it.
+func (ds *DebugSites) record(pc uintptr, format string, v []interface{}) *debugSite {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if format == "" && len(v) != 0 {
+		format, _ = v[0].(string)
+	}
+	s := &debugSite{DebugSite: DebugSite{
+		File:     frame.File,
+		Line:     frame.Line,
+		Function: frame.Function,
+		Format:   format,
+	}}
+
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	for i := len(ds.rules) - 1; i >= 0; i-- {
+		if s.match(ds.rules[i].pattern) {
+			s.setting = int32(ds.rules[i].setting)
+			break
+		}
+	}
+	actual, _ := ds.sites.LoadOrStore(pc, s)
+	return actual.(*debugSite)
+}
+
+func (s *debugSite) match(pattern string) bool {
+	fn := s.Function
+	if i := strings.LastIndexByte(fn, '/'); i != -1 {
+		fn = fn[i+1:]
+	}
+	file := trailingComponents(s.File, strings.Count(pattern, "/")+1)
+	for _, name := range []string{
+		s.Function,
+		fn,
+		fn[strings.IndexByte(fn, '.')+1:],
+		file,
+		file + ":" + strconv.Itoa(s.Line),
+	} {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
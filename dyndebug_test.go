@@ -0,0 +1,136 @@
+package alog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func debugAt(lg Logger, name string) {
+	lg.Debugf("debug %s", name)
+}
+
+func TestDynamicDebug(t *testing.T) {
+	sites := NewDebugSites()
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "", DynamicDebug(sites))
+
+	debugAt(lg, "first")
+	lg.WithField("k", "v").Debug("other site")
+	if n := len(sites.Sites()); n != 2 {
+		t.Fatalf("expected 2 sites, got %d", n)
+	}
+
+	n, err := sites.Set("debugAt", DebugOn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected pattern to match 1 site, got %d", n)
+	}
+	debugAt(lg, "second")
+	debugAt(lg.WithField("k", "v"), "third")
+	lg.Debug("not on")
+
+	// A rule applies to sites recorded after it is set.
+	if _, err = sites.Set("dyndebug_test.go", DebugOff); err != nil {
+		t.Fatal(err)
+	}
+	buf2 := new(bytes.Buffer)
+	lg2 := NewText(buf2, DebugLevel, " ", "", DynamicDebug(sites))
+	lg2.Debug("new site off")
+	lg2.Info("info not affected")
+	lg2.Close()
+	sites.Reset()
+	lg.Close()
+
+	if s := buf2.String(); strings.Contains(s, "new site off") || !strings.Contains(s, " INFO info not affected") {
+		t.Error("rule not applied to new site:", s)
+	}
+	expect := []string{
+		" DEBUG debug second",
+		" DEBUG debug third (k=v)",
+	}
+	checkLines(t, buf, expect...)
+	for _, site := range sites.Sites() {
+		if site.Setting != DebugDefault {
+			t.Error("expected site to be reset:", site)
+		}
+	}
+}
+
+func TestDebugSitesHandler(t *testing.T) {
+	sites := NewDebugSites()
+	lg := NewText(new(bytes.Buffer), InfoLevel, " ", "", DynamicDebug(sites))
+	debugAt(lg, "x")
+	lg.Close()
+
+	srv := httptest.NewServer(sites)
+	defer srv.Close()
+
+	rsp, err := http.PostForm(srv.URL, url.Values{"pattern": {"dyndebug_test.go:*"}, "setting": {"on"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Fatal("unexpected status:", rsp.Status)
+	}
+
+	rsp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	var list []map[string]interface{}
+	if err = json.NewDecoder(rsp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatal("expected 1 site, got", len(list))
+	}
+	site := list[0]
+	if site["setting"] != "on" || site["format"] != "debug %s" ||
+		!strings.HasSuffix(site["file"].(string), "dyndebug_test.go") ||
+		!strings.HasSuffix(site["function"].(string), ".debugAt") {
+		t.Error("wrong site:", site)
+	}
+
+	rsp2, err := http.PostForm(srv.URL, url.Values{"pattern": {"x"}, "setting": {"loud"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp2.Body.Close()
+	if rsp2.StatusCode != http.StatusBadRequest {
+		t.Error("expected bad request for invalid setting, got", rsp2.Status)
+	}
+}
+
+func logDebugAt(lg Logger) {
+	lg.Log(DebugLevel, "via Log")
+	lg.DebugFn(func() (string, Fields) { return "via DebugFn", nil })
+}
+
+func TestDynamicDebugLog(t *testing.T) {
+	sites := NewDebugSites()
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "", DynamicDebug(sites))
+
+	if _, err := sites.Set("logDebugAt", DebugOn); err != nil {
+		t.Fatal(err)
+	}
+	// Log at DebugLevel honors dynamic debug on the root Logger, as it does
+	// on a derived Logger.
+	logDebugAt(lg)
+	logDebugAt(lg.WithField("k", "v"))
+	lg.Close()
+
+	expect := " DEBUG via Log\n DEBUG via DebugFn\n DEBUG via Log (k=v)\n DEBUG via DebugFn (k=v)\n"
+	if buf.String() != expect {
+		t.Errorf("wrong output: %q", buf.String())
+	}
+}
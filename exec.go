@@ -0,0 +1,171 @@
+package alog
+
+import (
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// Fields of the entries logged by CaptureCmd.
+const (
+	PIDField     = "pid"
+	CommandField = "cmd"
+	StreamField  = "stream"
+)
+
+// CmdOptions configures CaptureCmd.
+type CmdOptions struct {
+	// StdoutLevel is the level of lines written to stdout.  If NoLevel,
+	// InfoLevel is used.
+	StdoutLevel Level
+
+	// StderrLevel is the level of lines written to stderr.  If NoLevel,
+	// WarnLevel is used.
+	StderrLevel Level
+
+	// ParseJSON enables parsing of lines that are JSON objects, as output by
+	// a JSON alog Logger in the child process.  Such a line is logged with
+	// the level, message, and fields of the child's entry, instead of as
+	// text.  The child's time is not kept.
+	ParseJSON bool
+}
+
+// CaptureCmd sets the stdout and stderr of cmd, which must not be started, to
+// log each line that the command writes as an entry.  Entries have the
+// PIDField, CommandField, and StreamField fields, with the stream being
+// "stdout" or "stderr".
+//
+// CaptureCmd returns a function that logs any last lines that do not end with
+// a newline.  Call it after cmd.Wait returns.
+func CaptureCmd(lg Logger, cmd *exec.Cmd, opts CmdOptions) (func(), error) {
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		return nil, errors.New("alog: command stdout or stderr already set")
+	}
+	if cmd.Process != nil {
+		return nil, errors.New("alog: command already started")
+	}
+	if opts.StdoutLevel == NoLevel {
+		opts.StdoutLevel = InfoLevel
+	}
+	if opts.StderrLevel == NoLevel {
+		opts.StderrLevel = WarnLevel
+	}
+	stdout := &cmdWriter{lg: lg, cmd: cmd, stream: "stdout", level: opts.StdoutLevel, parseJSON: opts.ParseJSON}
+	stderr := &cmdWriter{lg: lg, cmd: cmd, stream: "stderr", level: opts.StderrLevel, parseJSON: opts.ParseJSON}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return func() {
+		stdout.flush()
+		stderr.flush()
+	}, nil
+}
+
+// RunCmd runs cmd with its output captured by CaptureCmd, and waits for it to
+// complete.
+func RunCmd(lg Logger, cmd *exec.Cmd, opts CmdOptions) error {
+	flush, err := CaptureCmd(lg, cmd, opts)
+	if err != nil {
+		return err
+	}
+	err = cmd.Run()
+	flush()
+	return err
+}
+
+// cmdWriter is an io.Writer that logs each line written to it by a command.
+type cmdWriter struct {
+	lg        Logger
+	cmd       *exec.Cmd
+	stream    string
+	level     Level
+	parseJSON bool
+
+	mutex  sync.Mutex
+	tags   Fields
+	tagged Logger
+	lines  lineBuffer
+}
+
+// Write logs each complete line in p.
+func (w *cmdWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, line := range w.lines.add(p) {
+		w.logLine(line)
+	}
+	return len(p), nil
+}
+
+func (w *cmdWriter) flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if rest := w.lines.rest(); len(rest) != 0 {
+		w.logLine(rest)
+	}
+}
+
+func (w *cmdWriter) logLine(line []byte) {
+	// The PID is not known until the command is started, which happens
+	// before it writes output.
+	if w.tagged == nil {
+		w.tags = Fields{
+			CommandField: filepath.Base(w.cmd.Path),
+			StreamField:  w.stream,
+		}
+		if w.cmd.Process != nil {
+			w.tags[PIDField] = w.cmd.Process.Pid
+		}
+		w.tagged = w.lg.WithFields(w.tags)
+	}
+	if w.parseJSON && len(line) != 0 && line[0] == '{' {
+		if level, msg, fields, ok := parseJSONEntry(line); ok {
+			if level == NoLevel {
+				level = w.level
+			}
+			if w.lg.Enabled(level) {
+				w.lg.WithFields(fields).WithFields(w.tags).Log(level, msg)
+			}
+			return
+		}
+	}
+	if w.lg.Enabled(w.level) {
+		w.tagged.Log(w.level, string(line))
+	}
+}
+
+// parseJSONEntry parses a line formatted by the JSON Formatter.  It returns
+// false if the line is not a JSON object with a message.  Fields that the
+// Formatter renamed, to not conflict with the time, level, and message, are
+// given back their names.
+func parseJSONEntry(line []byte) (Level, string, Fields, bool) {
+	var fields Fields
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return NoLevel, "", nil, false
+	}
+	msg, ok := fields[msgField].(string)
+	if !ok {
+		return NoLevel, "", nil, false
+	}
+	level := NoLevel
+	if name, ok := fields[levelField].(string); ok {
+		if l, err := ParseLevel(name); err == nil {
+			level = l
+		}
+	}
+	delete(fields, msgField)
+	delete(fields, levelField)
+	delete(fields, timeField)
+	for ext, name := range map[string]string{
+		extMsgField:   msgField,
+		extLevelField: levelField,
+		extTimeField:  timeField,
+	} {
+		if v, ok := fields[ext]; ok {
+			fields[name] = v
+			delete(fields, ext)
+		}
+	}
+	return level, msg, fields, true
+}
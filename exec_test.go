@@ -0,0 +1,70 @@
+package alog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestHelperProcess is run as the child process of TestCaptureCmd.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("ALOG_HELPER_PROCESS") != "1" {
+		return
+	}
+	fmt.Println("hello")
+	fmt.Fprintln(os.Stderr, "oops")
+	lg := NewJSON(os.Stdout, DebugLevel, "")
+	lg.WithFields(Fields{"user": "rick", "level": "mine"}).Error("child failed")
+	lg.Close()
+	fmt.Print("{not json}\ntail")
+	os.Exit(0)
+}
+
+func TestCaptureCmd(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewJSON(buf, DebugLevel, "")
+	cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess")
+	cmd.Env = append(os.Environ(), "ALOG_HELPER_PROCESS=1")
+	if err := RunCmd(lg, cmd, CmdOptions{ParseJSON: true}); err != nil {
+		t.Fatal(err)
+	}
+	lg.Close()
+
+	pid := fmt.Sprintf(`"pid":%d`, cmd.Process.Pid)
+	for _, wants := range [][]string{
+		{`"level":"info"`, `"msg":"hello"`, `"stream":"stdout"`, pid, `"cmd":`},
+		{`"level":"warn"`, `"msg":"oops"`, `"stream":"stderr"`, pid},
+		{`"level":"error"`, `"msg":"child failed"`, `"user":"rick"`, `"fields.level":"mine"`, pid},
+		{`"level":"info"`, `"msg":"{not json}"`},
+		{`"level":"info"`, `"msg":"tail"`},
+	} {
+		found := false
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if containsAll(line, wants) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("no line with %q in:\n%s", wants, buf.String())
+		}
+	}
+
+	cmd = exec.Command(os.Args[0])
+	cmd.Stdout = os.Stdout
+	if _, err := CaptureCmd(lg, cmd, CmdOptions{}); err == nil {
+		t.Error("expected error when stdout is already set")
+	}
+}
+
+func containsAll(s string, subs []string) bool {
+	for _, sub := range subs {
+		if !strings.Contains(s, sub) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,29 +1,137 @@
 package alog
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"runtime"
 	"time"
 )
 
 type fieldLogger struct {
 	*logger
 	fields Fields
+	// gate, if not nil, decides whether an entry is queued.  It may replace
+	// the entry's fields, but must not modify the fields map.
+	gate func(ent *entry) bool
+	// name is the name given by Named, which selects the level set for the
+	// name by SetNameLevels.
+	name string
+	// off is true for the Logger returned by V when V-logging is disabled.
+	// It logs nothing, and Loggers created from it are itself.
+	off bool
+	// ctx is the context of an entry logged by a context-aware method, which
+	// is given to the hooks registered by RegisterContextHook.
+	ctx context.Context
+}
+
+// withGate returns a Logger that logs to lg, and that only queues entries
+// that pass gate, after any gate lg already has.  If lg is not created by this
+// package, it is returned unchanged.
+func withGate(lg Logger, gate func(ent *entry) bool) Logger {
+	switch l := lg.(type) {
+	case *logger:
+		return &fieldLogger{logger: l, gate: gate}
+	case *fieldLogger:
+		if l.off {
+			return l
+		}
+		if prev := l.gate; prev != nil {
+			next := gate
+			gate = func(ent *entry) bool { return prev(ent) && next(ent) }
+		}
+		return &fieldLogger{logger: l.logger, fields: l.fields, gate: gate, name: l.name}
+	}
+	return lg
+}
+
+// emit queues an entry with the fieldLogger's fields, if the entry's level is
+// logable.  It must be called directly by the exported method that is called
+// to log the entry.
+func (f *fieldLogger) emit(level Level, format string, v []interface{}, ln bool) {
+	logable := f.LogableAt(level)
+	if level == DebugLevel && f.debugSites != nil && !f.off {
+		logable = f.debugSites.enabled(logable, format, v)
+	}
+	if !logable {
+		return
+	}
+	f.queue(&entry{
+		ts:     time.Now(),
+		level:  level,
+		format: format,
+		args:   v,
+		fields: f.fields,
+		ln:     ln,
+	})
+}
+
+// emitFn queues an entry with the message and fields returned by fn, if the
+// entry's level is logable.  It must be called directly by the exported
+// method that is called to log the entry.
+func (f *fieldLogger) emitFn(level Level, fn func() (string, Fields)) {
+	logable := f.LogableAt(level)
+	if level == DebugLevel && f.debugSites != nil && !f.off {
+		logable = f.debugSites.enabled(logable, "", nil)
+	}
+	if !logable {
+		return
+	}
+	msg, fields := fn()
+	if len(f.fields) != 0 {
+		merged := make(Fields, len(f.fields)+len(fields))
+		for k, v := range f.fields {
+			merged[k] = v
+		}
+		for k, v := range fields {
+			merged[k] = v
+		}
+		fields = merged
+	}
+	f.queue(&entry{
+		ts:     time.Now(),
+		level:  level,
+		args:   []interface{}{msg},
+		fields: fields,
+	})
+}
+
+// queue queues an entry if it passes the gate.  If there is a gate, the
+// program counter of the log call is recorded in the entry, so queue must be
+// called directly by emit or emitFn.
+func (f *fieldLogger) queue(ent *entry) {
+	if f.gate != nil {
+		var pcs [1]uintptr
+		// Skip runtime.Callers, queue, emit or emitFn, and the log method.
+		runtime.Callers(4, pcs[:])
+		ent.pc = pcs[0]
+		if !f.gate(ent) {
+			return
+		}
+	}
+	if f.ctx != nil {
+		runContextHooks(f.ctx, ent)
+	}
+	f.entChan <- ent
 }
 
 func (f *fieldLogger) Print(v ...interface{}) {
-	f.entChan <- &entry{ts: time.Now(), args: v, fields: f.fields}
+	f.emit(NoLevel, "", v, false)
 }
 
 func (f *fieldLogger) Println(v ...interface{}) {
-	f.entChan <- &entry{ts: time.Now(), args: v, ln: true, fields: f.fields}
+	f.emit(NoLevel, "", v, true)
 }
 
 func (f *fieldLogger) Printf(format string, v ...interface{}) {
-	f.entChan <- &entry{ts: time.Now(), format: format, args: v, fields: f.fields}
+	f.emit(NoLevel, format, v, false)
 }
 
 func (f *fieldLogger) WithFields(fields Fields) Logger {
+	if f.off {
+		return f
+	}
 	// Create new fieldLogger with parents and specified fields.
 	newFields := make(Fields, len(f.fields)+len(fields))
 	for k, v := range f.fields {
@@ -35,6 +143,8 @@ func (f *fieldLogger) WithFields(fields Fields) Logger {
 	return &fieldLogger{
 		logger: f.logger,
 		fields: newFields,
+		gate:   f.gate,
+		name:   f.name,
 	}
 }
 
@@ -42,76 +152,148 @@ func (f *fieldLogger) WithField(key string, value interface{}) Logger {
 	return f.WithFields(Fields{key: value})
 }
 
+func (f *fieldLogger) WithError(err error) Logger {
+	return f.WithFields(Fields{ErrorField: err})
+}
+
+func (f *fieldLogger) Once(key string) Logger {
+	return withGate(f, f.onceGate(key))
+}
+
+func (f *fieldLogger) EveryN(n int) Logger {
+	return withGate(f, f.everyNGate(n))
+}
+
+func (f *fieldLogger) Every(interval time.Duration) Logger {
+	return withGate(f, f.everyGate(interval))
+}
+
+func (f *fieldLogger) RateLimited(rate float64, burst int) Logger {
+	return withGate(f, f.rateLimitGate(rate, burst))
+}
+
+func (f *fieldLogger) Enabled(level Level) bool {
+	return f.LogableAt(level)
+}
+
+func (f *fieldLogger) LogableAt(level Level) bool {
+	return !f.off && f.logableNamed(f.name, level)
+}
+
+func (f *fieldLogger) Writer(level Level, options ...WriterOption) io.WriteCloser {
+	if f.off {
+		return nopWriteCloser{io.Discard}
+	}
+	w := &logWriter{f: f, level: legacyLevel(level)}
+	for _, opt := range options {
+		opt(w)
+	}
+	return w
+}
+
 // ---- Leveled log functions -----
 
 func (f *fieldLogger) Panic(v ...interface{}) {
-	f.log(f.fields, PanicLevel, v)
+	f.emit(PanicLevel, "", v, false)
 	f.Close()
 	panic(fmt.Sprint(v...))
 }
 func (f *fieldLogger) Panicln(v ...interface{}) {
-	f.logln(f.fields, PanicLevel, v)
+	f.emit(PanicLevel, "", v, true)
 	f.Close()
 	panic(fmt.Sprint(v...))
 }
 func (f *fieldLogger) Panicf(format string, v ...interface{}) {
-	f.logf(f.fields, PanicLevel, format, v)
+	f.emit(PanicLevel, format, v, false)
 	f.Close()
 	panic(fmt.Sprintf(format, v...))
 }
 
 func (f *fieldLogger) Fatal(v ...interface{}) {
-	f.log(f.fields, FatalLevel, v)
+	f.emit(FatalLevel, "", v, false)
 	f.Close()
 	os.Exit(1)
 }
 func (f *fieldLogger) Fatalln(v ...interface{}) {
-	f.logln(f.fields, FatalLevel, v)
+	f.emit(FatalLevel, "", v, true)
 	f.Close()
 	os.Exit(1)
 }
 func (f *fieldLogger) Fatalf(format string, v ...interface{}) {
-	f.logf(f.fields, FatalLevel, format, v)
+	f.emit(FatalLevel, format, v, false)
 	f.Close()
 	os.Exit(1)
 }
 
 func (f *fieldLogger) Error(v ...interface{}) {
-	f.log(f.fields, ErrorLevel, v)
+	f.emit(ErrorLevel, "", v, false)
 }
 func (f *fieldLogger) Errorln(v ...interface{}) {
-	f.logln(f.fields, ErrorLevel, v)
+	f.emit(ErrorLevel, "", v, true)
 }
 func (f *fieldLogger) Errorf(format string, v ...interface{}) {
-	f.logf(f.fields, ErrorLevel, format, v)
+	f.emit(ErrorLevel, format, v, false)
 }
 
 func (f *fieldLogger) Warn(v ...interface{}) {
-	f.log(f.fields, WarnLevel, v)
+	f.emit(WarnLevel, "", v, false)
 }
 func (f *fieldLogger) Warnln(v ...interface{}) {
-	f.logln(f.fields, WarnLevel, v)
+	f.emit(WarnLevel, "", v, true)
 }
 func (f *fieldLogger) Warnf(format string, v ...interface{}) {
-	f.logf(f.fields, WarnLevel, format, v)
+	f.emit(WarnLevel, format, v, false)
 }
 
 func (f *fieldLogger) Info(v ...interface{}) {
-	f.log(f.fields, InfoLevel, v)
+	f.emit(InfoLevel, "", v, false)
 }
 func (f *fieldLogger) Infoln(v ...interface{}) {
-	f.logln(f.fields, InfoLevel, v)
+	f.emit(InfoLevel, "", v, true)
 }
 func (f *fieldLogger) Infof(format string, v ...interface{}) {
-	f.logf(f.fields, InfoLevel, format, v)
+	f.emit(InfoLevel, format, v, false)
 }
 
 func (f *fieldLogger) Debug(v ...interface{}) {
-	f.log(f.fields, DebugLevel, v)
+	f.emit(DebugLevel, "", v, false)
 }
 func (f *fieldLogger) Debugln(v ...interface{}) {
-	f.logln(f.fields, DebugLevel, v)
+	f.emit(DebugLevel, "", v, true)
 }
 func (f *fieldLogger) Debugf(format string, v ...interface{}) {
-	f.logf(f.fields, DebugLevel, format, v)
+	f.emit(DebugLevel, format, v, false)
+}
+
+func (f *fieldLogger) Trace(v ...interface{}) {
+	f.emit(TraceLevel, "", v, false)
+}
+func (f *fieldLogger) Traceln(v ...interface{}) {
+	f.emit(TraceLevel, "", v, true)
+}
+func (f *fieldLogger) Tracef(format string, v ...interface{}) {
+	f.emit(TraceLevel, format, v, false)
+}
+
+func (f *fieldLogger) Log(level Level, v ...interface{}) {
+	f.emit(legacyLevel(level), "", v, false)
+}
+func (f *fieldLogger) Logf(level Level, format string, v ...interface{}) {
+	f.emit(legacyLevel(level), format, v, false)
+}
+
+func (f *fieldLogger) ErrorFn(fn func() (string, Fields)) {
+	f.emitFn(ErrorLevel, fn)
+}
+func (f *fieldLogger) WarnFn(fn func() (string, Fields)) {
+	f.emitFn(WarnLevel, fn)
+}
+func (f *fieldLogger) InfoFn(fn func() (string, Fields)) {
+	f.emitFn(InfoLevel, fn)
+}
+func (f *fieldLogger) DebugFn(fn func() (string, Fields)) {
+	f.emitFn(DebugLevel, fn)
+}
+func (f *fieldLogger) TraceFn(fn func() (string, Fields)) {
+	f.emitFn(TraceLevel, fn)
 }
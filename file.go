@@ -0,0 +1,122 @@
+package alog
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// File is an io.Writer that writes to a log file at a fixed path, and that can
+// reopen that path.  This is for use with an external log rotation program,
+// such as logrotate, that renames the log file and then signals the program
+// to reopen it.
+//
+// A reopen requested by Reopen, or by a signal, is performed by the next call
+// to Write.  When a File is the output of a Logger, this means the reopen
+// happens on the Logger's writer goroutine, between entries, so no entry is
+// lost, duplicated, or split across files.
+type File struct {
+	path   string
+	reopen int32
+	mutex  sync.Mutex
+	file   *os.File
+	sigCh  chan os.Signal
+}
+
+// OpenFile opens the log file at path for appending, creating it if it does
+// not exist.
+func OpenFile(path string) (*File, error) {
+	file, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		path: path,
+		file: file,
+	}, nil
+}
+
+// Write writes p to the log file, first reopening the file if a reopen was
+// requested.
+//
+// If the file cannot be reopened, then p is written to the previously open
+// file and the reopen error is returned, after all of p is written, so that it
+// is reported through the Logger's ErrorHandler.  Reopening is tried again on
+// the next call to Reopen.
+func (f *File) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var reopenErr error
+	if atomic.CompareAndSwapInt32(&f.reopen, 1, 0) {
+		reopenErr = f.reopenFile()
+	}
+	n, err := f.file.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, reopenErr
+}
+
+// Reopen requests that the log file be reopened before the next write.
+func (f *File) Reopen() {
+	atomic.StoreInt32(&f.reopen, 1)
+}
+
+// ReopenOnSignal calls Reopen whenever one of the specified signals is
+// received.  If no signals are specified, then SIGHUP is used.  Signal
+// handling is stopped when the File is closed.
+func (f *File) ReopenOnSignal(sig ...os.Signal) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, sig...)
+
+	f.mutex.Lock()
+	if f.sigCh != nil {
+		signal.Stop(f.sigCh)
+		close(f.sigCh)
+	}
+	f.sigCh = sigCh
+	f.mutex.Unlock()
+
+	go func() {
+		for range sigCh {
+			f.Reopen()
+		}
+	}()
+}
+
+// Close stops any signal handling and closes the log file.  Close the Logger
+// that writes to the File before closing the File.
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.sigCh != nil {
+		signal.Stop(f.sigCh)
+		close(f.sigCh)
+		f.sigCh = nil
+	}
+	return f.file.Close()
+}
+
+// reopenFile opens a new file at the File's path, and closes the previous
+// file only if that succeeds.
+func (f *File) reopenFile() error {
+	file, err := openLogFile(f.path)
+	if err != nil {
+		return fmt.Errorf("cannot reopen log file: %w", err)
+	}
+	f.file.Close()
+	f.file = file
+	return nil
+}
+
+func openLogFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
@@ -0,0 +1,81 @@
+//go:build !windows
+// +build !windows
+
+package alog
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.ReopenOnSignal()
+
+	errCh := make(chan error, 1)
+	lg := NewText(f, InfoLevel, " ", "", ErrorHandler(func(err error) {
+		errCh <- err
+	}))
+
+	lg.Info("before rotate")
+	time.Sleep(50 * time.Millisecond)
+	if err = os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	time.Sleep(50 * time.Millisecond)
+	lg.Info("after rotate")
+	lg.Close()
+
+	data, _ := os.ReadFile(path + ".1")
+	if string(data) != " INFO before rotate\n" {
+		t.Errorf("wrong data in rotated file: %q", data)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != " INFO after rotate\n" {
+		t.Errorf("wrong data in reopened file: %q", data)
+	}
+	select {
+	case err = <-errCh:
+		t.Fatal("unexpected error:", err)
+	default:
+	}
+}
+
+func TestFileReopenFail(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	os.Mkdir(dir, 0755)
+	path := filepath.Join(dir, "app.log")
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	errCh := make(chan error, 1)
+	lg := NewText(f, InfoLevel, " ", "", ErrorHandler(func(err error) {
+		errCh <- err
+	}))
+
+	// Remove the log directory so that reopening fails.
+	os.RemoveAll(dir)
+	f.Reopen()
+	lg.Info("to old file")
+	lg.Close()
+
+	select {
+	case err = <-errCh:
+	default:
+		t.Fatal("expected reopen error")
+	}
+	t.Log("got expected error:", err)
+}
@@ -0,0 +1,423 @@
+package alog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Filter selects log entries using a filter expression.  Create a Filter with
+// ParseFilter.
+//
+// A filter expression is made of comparisons combined with && (and), || (or),
+// and ! (not), and grouped with parentheses.  && has higher precedence than
+// ||.  A comparison is a name, an operator, and a value:
+//
+//	level >= warn && component == "db" || msg =~ "timeout"
+//
+// The name "level" compares the entry's level by severity, so "level >= warn"
+// matches entries at WarnLevel, ErrorLevel, FatalLevel, and PanicLevel.
+// Entries without a level do not match any level comparison.  The value must
+// be a level name: panic, fatal, error, warn, info, debug, trace, or the name
+// of a level registered with RegisterLevel.
+//
+// The name "msg" refers to the entry's message.  Any other name refers to the
+// entry's field of that name.  A comparison with a field the entry does not
+// have does not match, except with != or !~.
+//
+// The operators == and != compare values as strings, with field values
+// formatted in the manner of fmt.Sprint.  The operators =~ and !~ match a
+// regular expression.  The operators <, <=, >, and >= compare numerically if
+// both sides are numbers, and otherwise compare as strings.
+//
+// Values are double-quoted strings, with Go escape sequences, or unquoted
+// words made of letters, digits, and any of "_.-:/".
+type Filter struct {
+	expr string
+	pred Predicate
+}
+
+// ParseFilter parses a filter expression into a Filter.
+func ParseFilter(expr string) (*Filter, error) {
+	p := &filterParser{input: expr}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return &Filter{expr: expr, pred: pred}, nil
+}
+
+// Match reports whether the entry matches the filter.  A nil Filter matches
+// all entries.
+func (f *Filter) Match(e *Entry) bool {
+	if f == nil {
+		return true
+	}
+	return f.pred(e)
+}
+
+// String returns the filter expression.
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+// filterValue holds a *Filter that can be swapped at runtime.
+type filterValue struct {
+	v atomic.Value
+}
+
+func (fv *filterValue) load() *Filter {
+	f, _ := fv.v.Load().(*Filter)
+	return f
+}
+
+func (fv *filterValue) store(f *Filter) {
+	fv.v.Store(f)
+}
+
+// FilterSink is a Sink that only writes entries that match its Filter to
+// another Sink.  The Filter can be changed at any time.
+type FilterSink struct {
+	sink   Sink
+	filter filterValue
+}
+
+// NewFilterSink creates a FilterSink that writes entries that match filter
+// to sink.  If filter is nil, all entries are written.
+func NewFilterSink(sink Sink, filter *Filter) *FilterSink {
+	fs := &FilterSink{sink: sink}
+	fs.filter.store(filter)
+	return fs
+}
+
+// SetFilter replaces the sink's Filter.  If filter is nil, all entries are
+// written.
+func (fs *FilterSink) SetFilter(filter *Filter) {
+	fs.filter.store(filter)
+}
+
+// Filter returns the sink's current Filter.
+func (fs *FilterSink) Filter() *Filter {
+	return fs.filter.load()
+}
+
+func (fs *FilterSink) WriteEntry(e *Entry) error {
+	if !fs.filter.load().Match(e) {
+		return nil
+	}
+	return fs.sink.WriteEntry(e)
+}
+
+// ---- Filter expression parser ----
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+type filterParser struct {
+	input string
+	pos   int
+	tok   token
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("filter: at position %d: %s", p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("_.-:/", c) != -1
+}
+
+// next reads the next token from the input.
+func (p *filterParser) next() error {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+	start := p.pos
+	p.tok = token{pos: start}
+	if p.pos >= len(p.input) {
+		return nil
+	}
+
+	rest := p.input[p.pos:]
+	for _, op := range []struct {
+		text string
+		kind tokenKind
+	}{
+		{"&&", tokAnd}, {"||", tokOr}, {"==", tokOp}, {"!=", tokOp},
+		{"=~", tokOp}, {"!~", tokOp}, {">=", tokOp}, {"<=", tokOp},
+		{">", tokOp}, {"<", tokOp}, {"!", tokNot}, {"(", tokLParen},
+		{")", tokRParen},
+	} {
+		if strings.HasPrefix(rest, op.text) {
+			p.pos += len(op.text)
+			p.tok.kind = op.kind
+			p.tok.text = op.text
+			return nil
+		}
+	}
+
+	c := p.input[p.pos]
+	if c == '"' {
+		p.pos++
+		for p.pos < len(p.input) && p.input[p.pos] != '"' {
+			if p.input[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.input) {
+			return p.errorf("unterminated string")
+		}
+		p.pos++
+		s, err := strconv.Unquote(p.input[start:p.pos])
+		if err != nil {
+			return p.errorf("invalid string %s", p.input[start:p.pos])
+		}
+		p.tok.kind = tokString
+		p.tok.text = s
+		return nil
+	}
+	if isWordChar(c) {
+		for p.pos < len(p.input) && isWordChar(p.input[p.pos]) {
+			p.pos++
+		}
+		p.tok.kind = tokWord
+		p.tok.text = p.input[start:p.pos]
+		return nil
+	}
+	return p.errorf("unexpected character %q", c)
+}
+
+func (p *filterParser) parseOr() (Predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *Entry) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokAnd {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *Entry) bool { return l(e) && right(e) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Predicate, error) {
+	switch p.tok.kind {
+	case tokNot:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		pred, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e *Entry) bool { return !pred(e) }, nil
+	case tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected \")\", found %s", p.tok)
+		}
+		return pred, p.next()
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (Predicate, error) {
+	if p.tok.kind != tokWord {
+		return nil, p.errorf("expected name, found %s", p.tok)
+	}
+	name := p.tok.text
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokOp {
+		return nil, p.errorf("expected comparison operator, found %s", p.tok)
+	}
+	op := p.tok.text
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokWord && p.tok.kind != tokString {
+		return nil, p.errorf("expected value, found %s", p.tok)
+	}
+	value := p.tok.text
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if name == "level" {
+		return levelComparison(op, value)
+	}
+
+	var get func(e *Entry) (string, bool)
+	if name == "msg" {
+		get = func(e *Entry) (string, bool) { return e.Message, true }
+	} else {
+		get = func(e *Entry) (string, bool) {
+			v, ok := e.Fields[name]
+			if !ok {
+				return "", false
+			}
+			if s, ok := v.(string); ok {
+				return s, true
+			}
+			return fmt.Sprint(v), true
+		}
+	}
+
+	switch op {
+	case "=~", "!~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("filter: invalid regular expression %q: %w", value, err)
+		}
+		match := op == "=~"
+		return func(e *Entry) bool {
+			s, ok := get(e)
+			return ok && re.MatchString(s) == match
+		}, nil
+	case "==":
+		return func(e *Entry) bool {
+			s, ok := get(e)
+			return ok && s == value
+		}, nil
+	case "!=":
+		return func(e *Entry) bool {
+			s, ok := get(e)
+			return !ok || s != value
+		}, nil
+	}
+
+	num, err := strconv.ParseFloat(value, 64)
+	isNum := err == nil
+	return func(e *Entry) bool {
+		s, ok := get(e)
+		if !ok {
+			return false
+		}
+		var cmp int
+		if n, err := strconv.ParseFloat(s, 64); isNum && err == nil {
+			switch {
+			case n < num:
+				cmp = -1
+			case n > num:
+				cmp = 1
+			}
+		} else {
+			cmp = strings.Compare(s, value)
+		}
+		return compareResult(op, cmp)
+	}, nil
+}
+
+func levelComparison(op, value string) (Predicate, error) {
+	level, err := ParseLevel(value)
+	if err != nil || level == NoLevel {
+		return nil, fmt.Errorf("filter: invalid level %q", value)
+	}
+	switch op {
+	case "=~", "!~":
+		return nil, fmt.Errorf("filter: operator %s cannot be used with level", op)
+	}
+	return func(e *Entry) bool {
+		if e.Level == NoLevel {
+			return false
+		}
+		// Lower level values are more severe, so reverse the comparison.
+		var cmp int
+		switch {
+		case e.Level > level:
+			cmp = -1
+		case e.Level < level:
+			cmp = 1
+		}
+		return compareResult(op, cmp)
+	}, nil
+}
+
+// compareResult applies a comparison operator to the result of comparing two
+// values, which is -1, 0, or 1.
+func compareResult(op string, cmp int) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package alog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+	entries := []*Entry{
+		{Level: WarnLevel, Message: "slow query", Fields: Fields{"component": "db", "ms": 250}},
+		{Level: InfoLevel, Message: "request timeout", Fields: Fields{"component": "http"}},
+		{Level: ErrorLevel, Message: "failed", Fields: Fields{"component": "http"}},
+		{Level: NoLevel, Message: "printed"},
+	}
+	tests := []struct {
+		expr  string
+		match []bool
+	}{
+		{`level >= warn && component == "db" || msg =~ "timeout"`, []bool{true, true, false, false}},
+		{`level >= warn && (component == db || msg =~ timeout)`, []bool{true, false, false, false}},
+		{`level < warn`, []bool{false, true, false, false}},
+		{`!(component == http)`, []bool{true, false, false, true}},
+		{`component != http`, []bool{true, false, false, true}},
+		{`ms > 100 && ms <= 250`, []bool{true, false, false, false}},
+		{`msg !~ "^(slow|failed)"`, []bool{false, true, false, true}},
+	}
+	for _, test := range tests {
+		f, err := ParseFilter(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, e := range entries {
+			if f.Match(e) != test.match[i] {
+				t.Errorf("%s: expected %v for entry %d", test.expr, test.match[i], i)
+			}
+		}
+	}
+
+	for _, expr := range []string{
+		`level >= loud`, `component ==`, `(a == b`, `a == "b`, `a b`, `msg =~ "("`,
+	} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("expected error parsing %s", expr)
+		} else {
+			t.Log(err)
+		}
+	}
+}
+
+func TestSetFilter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, DebugLevel, " ", "")
+	f, err := ParseFilter(`level >= warn || component == db`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg.SetFilter(f)
+	lg.Info("hidden")
+	lg.WithField("component", "db").Debug("db debug")
+	lg.Error("an error")
+	time.Sleep(50 * time.Millisecond)
+	lg.SetFilter(nil)
+	lg.Info("shown")
+	lg.Close()
+
+	expect := " DEBUG db debug (component=db)\n ERROR an error\n INFO shown\n"
+	if buf.String() != expect {
+		t.Errorf("wrong output: %q", buf.String())
+	}
+}
+
+func TestSetFilterOnChild(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+	child := lg.Named("child").WithField("k", 1)
+	sibling := lg.Named("sibling")
+
+	f, err := ParseFilter(`level >= error`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Filter set on a child applies to the root and its other children.
+	child.SetFilter(f)
+	lg.Info("root hidden")
+	sibling.Info("sibling hidden")
+	child.Error("child error")
+	time.Sleep(50 * time.Millisecond)
+	sibling.SetFilter(nil)
+	lg.Info("root shown")
+	lg.Close()
+
+	checkLines(t, buf, " ERROR child error", " INFO root shown")
+}
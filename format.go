@@ -0,0 +1,146 @@
+package alog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// ErrorField is exported so it can be used explicitly as a field name.
+	ErrorField = "error"
+
+	levelField    = "level"
+	extLevelField = "fields.level"
+	msgField      = "msg"
+	extMsgField   = "fields.msg"
+	timeField     = "time"
+	extTimeField  = "fields.time"
+)
+
+const defaultTimeLayout = "Jan 02 15:04:05"
+
+// Entry is a log entry that is ready to be formatted.
+type Entry struct {
+	// Time is when the entry was logged.
+	Time time.Time
+	// Level is the severity level of the entry.  It is NoLevel if the entry
+	// was logged by Print, or if leveled logging is disabled.
+	Level Level
+	// Message is the log message, formatted from the logged arguments.
+	Message string
+	// Fields are the fields the entry was logged with.  The Fields map may be
+	// shared with other entries and must not be modified.
+	Fields Fields
+}
+
+// Formatter formats log entries for output.  A Formatter is only called by
+// the goroutine that writes log entries, so it does not need to be safe for
+// concurrent use by the same Logger.
+type Formatter interface {
+	// Format appends the formatted entry to buf, and returns the extended
+	// buffer.
+	Format(buf []byte, e *Entry) ([]byte, error)
+}
+
+// resolveTimeLayout returns the timestamp layout to use for the given layout
+// argument.
+func resolveTimeLayout(layout string) string {
+	if layout == "" {
+		return defaultTimeLayout
+	}
+	return strings.TrimSpace(layout)
+}
+
+type textFormatter struct {
+	tsLayout string
+	prefix   string
+}
+
+// NewTextFormatter creates a Formatter that formats entries as semi-structured
+// text lines, as output by loggers created with NewText.
+//
+// The timeLayout defines the timestamp format according to time.Format.  If
+// not specified, defaults to "Jan 02 15:04:05".  To disable timestamp output,
+// specify a TimeLayout string consisting on one or more spaces. The prefix
+// appears at the beginning of each generated log line.
+func NewTextFormatter(timeLayout, prefix string) Formatter {
+	return &textFormatter{
+		tsLayout: resolveTimeLayout(timeLayout),
+		prefix:   prefix,
+	}
+}
+
+func (f *textFormatter) Format(buf []byte, e *Entry) ([]byte, error) {
+	if f.prefix != "" {
+		buf = append(buf, f.prefix...)
+	}
+	if f.tsLayout != "" {
+		buf = e.Time.AppendFormat(buf, f.tsLayout)
+	}
+	if e.Level != NoLevel {
+		buf = append(buf, e.Level.text()...)
+	} else {
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, e.Message...)
+	for k, v := range e.Fields {
+		buf = append(buf, " ("...)
+		buf = append(buf, k...)
+		buf = append(buf, '=')
+		buf = append(buf, fmt.Sprint(v)...)
+		buf = append(buf, ')')
+	}
+	return append(buf, '\n'), nil
+}
+
+type jsonFormatter struct {
+	tsLayout string
+}
+
+// NewJSONFormatter creates a Formatter that formats entries as JSON objects,
+// one per line, as output by loggers created with NewJSON.
+//
+// The timeLayout defines the timestamp format according to time.Format.  If
+// not specified, defaults to "Jan 02 15:04:05".  To disable timestamp output,
+// specify a TimeLayout string consisting on one or more spaces.
+func NewJSONFormatter(timeLayout string) Formatter {
+	return &jsonFormatter{
+		tsLayout: resolveTimeLayout(timeLayout),
+	}
+}
+
+func (f *jsonFormatter) Format(buf []byte, e *Entry) ([]byte, error) {
+	fields := make(map[string]interface{}, len(e.Fields)+3)
+	for k, v := range e.Fields {
+		// Convert any error types to string.
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fields[k] = v
+	}
+	if f.tsLayout != "" {
+		if v, ok := fields[timeField]; ok {
+			fields[extTimeField] = v
+		}
+		fields[timeField] = e.Time.Format(f.tsLayout)
+	}
+	if e.Level != NoLevel {
+		if v, ok := fields[levelField]; ok {
+			fields[extLevelField] = v
+		}
+		fields[levelField] = e.Level.String()
+	}
+	if v, ok := fields[msgField]; ok {
+		fields[extMsgField] = v
+	}
+	fields[msgField] = e.Message
+
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return buf, fmt.Errorf("failed to marshal fields to JSON: %w", err)
+	}
+	buf = append(buf, encoded...)
+	return append(buf, '\n'), nil
+}
@@ -0,0 +1,255 @@
+package alog
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Fields of the request-scoped Logger and access entries of HTTPMiddleware.
+const (
+	MethodField     = "method"
+	PathField       = "path"
+	RequestIDField  = "request_id"
+	RemoteAddrField = "remote_addr"
+	StatusField     = "status"
+	BytesField      = "bytes"
+	DurationField   = "duration"
+)
+
+// DefaultRequestIDHeader is the header that HTTPMiddleware reads and writes
+// the request ID in, unless HTTPOptions specifies another.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// AccessLogFormat selects the format of the access entries of HTTPMiddleware.
+type AccessLogFormat int
+
+const (
+	// AccessLogFields logs access entries with the message "METHOD path
+	// status", and with the fields of the request-scoped Logger, plus the
+	// StatusField, BytesField, and DurationField fields.
+	AccessLogFields AccessLogFormat = iota
+	// CommonLogFormat logs access entries with the Apache Common Log Format
+	// line as the message, and no fields.
+	CommonLogFormat
+	// CombinedLogFormat logs access entries with the Apache Combined Log
+	// Format line, which adds the referer and user agent to the Common Log
+	// Format, as the message, and no fields.
+	CombinedLogFormat
+)
+
+// HTTPOptions configures HTTPMiddleware.
+type HTTPOptions struct {
+	// Format is the format of access entries.
+	Format AccessLogFormat
+
+	// Level is the level of access entries.  If NoLevel, InfoLevel is used.
+	// To log the Common or Combined Log Format lines as they are, use a
+	// Logger that has leveled logging disabled, and no time or prefix.
+	Level Level
+
+	// RequestIDHeader is the header that holds the request ID.  If empty,
+	// DefaultRequestIDHeader is used.
+	RequestIDHeader string
+
+	// NewRequestID generates the ID of a request that does not have one.  If
+	// nil, a random 16 digit hex string is generated.
+	NewRequestID func() string
+}
+
+type reqIDKey struct{}
+
+// RequestID returns the request ID that HTTPMiddleware stored in ctx, or an
+// empty string if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(reqIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// HTTPMiddleware returns middleware that logs HTTP requests to lg.
+//
+// Each request is given a request-scoped Logger, with the MethodField,
+// PathField, RequestIDField, and RemoteAddrField fields, that handlers get
+// with FromContext(r.Context()).  The request ID is taken from the request's
+// request ID header, or generated if the header is absent, and is set in the
+// same header of the response.  When the handler returns, one access entry is
+// logged, in the format given by opts.  If the handler panics, the entry is
+// logged, with status 500 if the handler did not write the header, and the
+// panic continues.
+func HTTPMiddleware(lg Logger, opts HTTPOptions) func(http.Handler) http.Handler {
+	if opts.Level == NoLevel {
+		opts.Level = InfoLevel
+	}
+	if opts.RequestIDHeader == "" {
+		opts.RequestIDHeader = DefaultRequestIDHeader
+	}
+	if opts.NewRequestID == nil {
+		opts.NewRequestID = newRequestID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(opts.RequestIDHeader)
+			if id == "" {
+				id = opts.NewRequestID()
+			}
+			w.Header().Set(opts.RequestIDHeader, id)
+
+			reqLogger := lg.WithFields(Fields{
+				MethodField:     r.Method,
+				PathField:       r.URL.Path,
+				RequestIDField:  id,
+				RemoteAddrField: r.RemoteAddr,
+			})
+			ctx := context.WithValue(r.Context(), reqIDKey{}, id)
+			ctx = NewContext(ctx, reqLogger)
+			rw := &responseWriter{ResponseWriter: w}
+			// The entry is logged by a deferred function, so that a request
+			// whose handler panics is logged before the panic continues.  The
+			// status is 500, unless the handler wrote the header before it
+			// panicked.
+			defer func() {
+				if v := recover(); v != nil {
+					if rw.status == 0 {
+						rw.status = http.StatusInternalServerError
+					}
+					logAccess(lg, reqLogger, opts, r, start, rw)
+					panic(v)
+				}
+				logAccess(lg, reqLogger, opts, r, start, rw)
+			}()
+			next.ServeHTTP(rw, r.WithContext(ctx))
+		})
+	}
+}
+
+// logAccess logs the access entry of a request.
+func logAccess(lg, reqLogger Logger, opts HTTPOptions, r *http.Request, start time.Time, rw *responseWriter) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	switch opts.Format {
+	case CommonLogFormat, CombinedLogFormat:
+		line := appendCommonLog(nil, r, start, rw.status, rw.bytes)
+		if opts.Format == CombinedLogFormat {
+			line = append(line, ' ')
+			line = appendQuoted(line, r.Referer())
+			line = append(line, ' ')
+			line = appendQuoted(line, r.UserAgent())
+		}
+		lg.Log(opts.Level, string(line))
+	default:
+		reqLogger.WithFields(Fields{
+			StatusField:   rw.status,
+			BytesField:    rw.bytes,
+			DurationField: time.Since(start),
+		}).Logf(opts.Level, "%s %s %d", r.Method, r.URL.Path, rw.status)
+	}
+}
+
+// appendCommonLog appends a Common Log Format line:
+//
+//	host ident authuser [date] "request" status bytes
+func appendCommonLog(buf []byte, r *http.Request, ts time.Time, status int, size int64) []byte {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := "-"
+	if r.URL.User != nil && r.URL.User.Username() != "" {
+		user = r.URL.User.Username()
+	} else if name, _, ok := r.BasicAuth(); ok && name != "" {
+		user = name
+	}
+	buf = append(buf, host...)
+	buf = append(buf, " - "...)
+	buf = append(buf, user...)
+	buf = append(buf, " ["...)
+	buf = ts.AppendFormat(buf, "02/Jan/2006:15:04:05 -0700")
+	buf = append(buf, "] "...)
+	buf = appendQuoted(buf, r.Method+" "+r.URL.RequestURI()+" "+r.Proto)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(status), 10)
+	buf = append(buf, ' ')
+	if size == 0 {
+		return append(buf, '-')
+	}
+	return strconv.AppendInt(buf, size, 10)
+}
+
+// appendQuoted appends s in double quotes, escaping quotes, backslashes, and
+// control characters.  An empty s is logged as "-".
+func appendQuoted(buf []byte, s string) []byte {
+	if s == "" {
+		s = "-"
+	}
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c < ' ' || c == 0x7f:
+			buf = append(buf, `\x`...)
+			buf = append(buf, hex.EncodeToString([]byte{c})...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
+
+// responseWriter records the status and size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("response does not support hijacking")
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,124 @@
+package alog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+
+	var handlerID string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerID = RequestID(r.Context())
+		FromContext(r.Context()).Info("handling")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	})
+	mw := HTTPMiddleware(lg, HTTPOptions{NewRequestID: func() string { return "gen-1" }})
+
+	req := httptest.NewRequest("GET", "/pot?x=1", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	mw(handler).ServeHTTP(rec, req)
+
+	req = httptest.NewRequest("GET", "/pot", nil)
+	req.Header.Set("X-Request-ID", "given")
+	rec2 := httptest.NewRecorder()
+	mw(handler).ServeHTTP(rec2, req)
+	lg.Close()
+
+	if handlerID != "given" || rec.Header().Get("X-Request-ID") != "gen-1" ||
+		rec2.Header().Get("X-Request-ID") != "given" {
+		t.Error("wrong request IDs:", handlerID, rec.Header(), rec2.Header())
+	}
+
+	lines := checkLines(t, buf, " INFO handling", " INFO GET /pot 418", " INFO handling", " INFO GET /pot 418")
+	for _, want := range []string{" INFO handling", "(method=GET)", "(path=/pot)",
+		"(request_id=gen-1)", "(remote_addr=10.0.0.1:1234)"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("missing %q in %q", want, lines[0])
+		}
+	}
+	for _, want := range []string{" INFO GET /pot 418", "(status=418)", "(bytes=15)",
+		"(duration=", "(request_id=gen-1)"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("missing %q in %q", want, lines[1])
+		}
+	}
+}
+
+func TestHTTPMiddlewareCombined(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := New(buf, NoLevel, NewTextFormatter("", ""))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	mw := HTTPMiddleware(lg, HTTPOptions{Format: CombinedLogFormat})
+	req := httptest.NewRequest("POST", "/a%20b?q=\"x\"", nil)
+	req.RemoteAddr = "192.0.2.7:5555"
+	req.SetBasicAuth("frank", "secret")
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", "test/1.0")
+	mw(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	mw = HTTPMiddleware(lg, HTTPOptions{Format: CommonLogFormat})
+	req = httptest.NewRequest("GET", "/empty", nil)
+	req.RemoteAddr = "192.0.2.8:1"
+	mw(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), req)
+	lg.Close()
+
+	combined := regexp.MustCompile(`192\.0\.2\.7 - frank \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [-+]\d{4}\] ` +
+		`"POST /a%20b\?q=\\"x\\" HTTP/1\.1" 200 5 "http://example\.com/" "test/1\.0"`)
+	if !combined.MatchString(buf.String()) {
+		t.Error("wrong combined log format:", buf.String())
+	}
+	common := regexp.MustCompile(`192\.0\.2\.8 - - \[[^]]+\] "GET /empty HTTP/1\.1" 404 19\n`)
+	if !common.MatchString(buf.String()) {
+		t.Error("wrong common log format:", buf.String())
+	}
+}
+
+func TestHTTPMiddlewarePanic(t *testing.T) {
+	for _, tc := range []struct {
+		path    string
+		handler http.HandlerFunc
+		status  string
+	}{
+		{"/boom", func(w http.ResponseWriter, r *http.Request) {
+			panic("handler failed")
+		}, "500"},
+		// The status that the client received is logged.
+		{"/late", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("partial"))
+			panic("handler failed")
+		}, "200"},
+	} {
+		buf := new(bytes.Buffer)
+		lg := NewJSON(buf, InfoLevel, "")
+		mw := HTTPMiddleware(lg, HTTPOptions{})
+		func() {
+			defer func() {
+				if r := recover(); r != "handler failed" {
+					t.Error("expected panic to continue, got", r)
+				}
+			}()
+			mw(tc.handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tc.path, nil))
+		}()
+		lg.Close()
+
+		for _, want := range []string{`"status":` + tc.status, `"path":"` + tc.path + `"`,
+			`"msg":"GET ` + tc.path + " " + tc.status + `"`} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("missing %s in %s", want, buf.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Package journald provides a Formatter and Writer that send alog entries to the
+systemd journal using the journald native protocol.
+
+Unlike writing text to stdout, this keeps the fields of each entry as separate
+journal fields:
+
+	w, err := journald.Dial(journald.SocketPath)
+	if err != nil {
+		...
+	}
+	logger := alog.New(w, alog.InfoLevel, journald.NewFormatter("myapp"))
+
+Each entry's message is sent as MESSAGE, its level as PRIORITY, and each of its
+fields as a journal field with the key converted to uppercase and sanitized as
+journald requires.  A field whose name would be MESSAGE, PRIORITY, or
+SYSLOG_IDENTIFIER is sent with the name prefixed by "FIELDS_", such as
+FIELDS_MESSAGE, in the manner of the alog JSON Formatter's "fields.msg".
+*/
+package journald
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gammazero/alog"
+)
+
+// maxFieldName is the maximum length of a journal field name.
+const maxFieldName = 64
+
+type formatter struct {
+	identifier string
+}
+
+// NewFormatter creates a Formatter that formats entries as journald native
+// protocol messages.  If identifier is not empty, each message includes it as
+// SYSLOG_IDENTIFIER.
+//
+// Each entry's level is mapped to a PRIORITY by alog.SyslogSeverity, in the
+// same way as the alog syslog Formatter.
+func NewFormatter(identifier string) alog.Formatter {
+	return &formatter{identifier: identifier}
+}
+
+func (f *formatter) Format(buf []byte, e *alog.Entry) ([]byte, error) {
+	buf = appendField(buf, "PRIORITY", strconv.Itoa(alog.SyslogSeverity(e.Level)))
+	if f.identifier != "" {
+		buf = appendField(buf, "SYSLOG_IDENTIFIER", f.identifier)
+	}
+	buf = appendField(buf, "MESSAGE", e.Message)
+	for k, v := range e.Fields {
+		name := FieldName(k)
+		switch name {
+		case "":
+			continue
+		case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+			name = "FIELDS_" + name
+		}
+		buf = appendField(buf, name, fmt.Sprint(v))
+	}
+	return buf, nil
+}
+
+// appendField appends a field in the native protocol format.  A value that
+// contains no newlines is written as NAME=value.  Otherwise, it is written as
+// the name, a newline, the value's length as a little-endian 64-bit integer,
+// and the value.  Either form is terminated by a newline.
+func appendField(buf []byte, name, value string) []byte {
+	buf = append(buf, name...)
+	if strings.IndexByte(value, '\n') == -1 {
+		buf = append(buf, '=')
+	} else {
+		buf = append(buf, '\n')
+		var size [8]byte
+		binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+		buf = append(buf, size[:]...)
+	}
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+// FieldName converts an alog field key into a journal field name.  Letters
+// are converted to uppercase, characters other than letters, digits, and
+// underscores are replaced by underscores, and leading underscores, which
+// journald reserves for trusted fields, are removed.  A name that would start
+// with a digit is prefixed with "F_", and names are truncated to 64
+// characters.  An empty string is returned if no valid name remains.
+func FieldName(key string) string {
+	name := make([]byte, 0, len(key)+2)
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+		if c == '_' && len(name) == 0 {
+			continue
+		}
+		name = append(name, c)
+	}
+	if len(name) == 0 {
+		return ""
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = append([]byte("F_"), name...)
+	}
+	if len(name) > maxFieldName {
+		name = name[:maxFieldName]
+	}
+	return string(name)
+}
@@ -0,0 +1,113 @@
+package journald
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// SocketPath is the path of the journald native protocol socket.
+const SocketPath = "/run/systemd/journal/socket"
+
+// Writer is an io.Writer that sends each write as a message to journald.  It
+// is used as the output of a Logger that formats entries with the Formatter
+// from this package.
+type Writer struct {
+	mutex sync.Mutex
+	conn  *net.UnixConn
+}
+
+// Dial connects to the journald socket at path, which is normally SocketPath.
+func Dial(path string) (*Writer, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{conn: conn}, nil
+}
+
+// Write sends p as a single journal message.  If p is too large to send as a
+// datagram, it is written to a sealed memfd, and the file descriptor is sent
+// to journald instead.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	_, err := w.conn.Write(p)
+	if err == nil {
+		return len(p), nil
+	}
+	if !errors.Is(err, unix.EMSGSIZE) && !errors.Is(err, unix.ENOBUFS) {
+		return 0, err
+	}
+
+	file, err := tempMessageFile(p)
+	if err != nil {
+		return 0, fmt.Errorf("cannot create file for large journal message: %w", err)
+	}
+	defer file.Close()
+	if err = w.sendFd(int(file.Fd())); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sendFd sends an empty datagram that passes the file descriptor to journald.
+func (w *Writer) sendFd(fd int) error {
+	rawConn, err := w.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sendErr error
+	err = rawConn.Write(func(sock uintptr) bool {
+		sendErr = unix.Sendmsg(int(sock), nil, unix.UnixRights(fd), nil, 0)
+		return sendErr != unix.EAGAIN
+	})
+	if err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// Close closes the connection to journald.
+func (w *Writer) Close() error {
+	return w.conn.Close()
+}
+
+// tempMessageFile returns a file containing p, to pass to journald.  The file
+// is a sealed memfd if possible.  Otherwise, it is an unlinked temporary file
+// in /dev/shm, which journald also accepts.
+func tempMessageFile(p []byte) (*os.File, error) {
+	fd, err := unix.MemfdCreate("journal-message", unix.MFD_ALLOW_SEALING|unix.MFD_CLOEXEC)
+	if err != nil {
+		return tempShmFile(p)
+	}
+	file := os.NewFile(uintptr(fd), "journal-message")
+	if _, err = file.Write(p); err != nil {
+		file.Close()
+		return nil, err
+	}
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err = unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func tempShmFile(p []byte) (*os.File, error) {
+	file, err := os.CreateTemp("/dev/shm", "journal-message-")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(file.Name())
+	if _, err = file.Write(p); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
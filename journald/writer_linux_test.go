@@ -0,0 +1,93 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gammazero/alog"
+	"golang.org/x/sys/unix"
+)
+
+// readMessage reads one journal message from the socket, reading it from a
+// passed file descriptor if one is sent.
+func readMessage(t *testing.T, conn *net.UnixConn) []byte {
+	buf := make([]byte, 64*1024)
+	oob := make([]byte, unix.CmsgSpace(4))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return buf[:n]
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fds, err := unix.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "journal-message")
+	defer file.Close()
+	// The file offset is shared with the sender, so read from the start.
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 1<<30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := Dial(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	lg := alog.New(w, alog.DebugLevel, NewFormatter("testapp"))
+
+	lg.WithFields(alog.Fields{"request-id": 42, "_hidden": "x", "2fa": "ok",
+		"message": "own", "priority": 9}).Warn("multi\nline")
+	msg := readMessage(t, conn)
+
+	for _, expect := range []string{
+		"PRIORITY=4\n", "SYSLOG_IDENTIFIER=testapp\n", "REQUEST_ID=42\n",
+		"HIDDEN=x\n", "F_2FA=ok\n", "FIELDS_MESSAGE=own\n", "FIELDS_PRIORITY=9\n"} {
+		if !bytes.Contains(msg, []byte(expect)) {
+			t.Errorf("missing %q in message %q", expect, msg)
+		}
+	}
+	if bytes.Count(msg, []byte("\nPRIORITY=")) != 0 || bytes.Count(msg, []byte("\nMESSAGE")) != 1 {
+		t.Errorf("duplicate PRIORITY or MESSAGE in message %q", msg)
+	}
+	binMsg := []byte("MESSAGE\n")
+	binMsg = binary.LittleEndian.AppendUint64(binMsg, uint64(len("multi\nline")))
+	binMsg = append(binMsg, "multi\nline\n"...)
+	if !bytes.Contains(msg, binMsg) {
+		t.Errorf("missing binary MESSAGE field in %q", msg)
+	}
+
+	// Send a message too large for a datagram.
+	large := strings.Repeat("x", 1<<20)
+	lg.Info(large)
+	msg = readMessage(t, conn)
+	if !bytes.Contains(msg, []byte("MESSAGE="+large+"\n")) {
+		t.Error("large message not received")
+	}
+	lg.Close()
+}
@@ -0,0 +1,49 @@
+package alog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+
+	if !lg.Enabled(InfoLevel) || lg.Enabled(DebugLevel) {
+		t.Error("wrong levels enabled")
+	}
+
+	var calls int
+	expensive := func() (string, Fields) {
+		calls++
+		return "expensive", Fields{"n": calls}
+	}
+	lg.DebugFn(expensive)
+	lg.WithField("a", 1).DebugFn(expensive)
+	lg.WithField("a", 1).InfoFn(expensive)
+
+	var lazyCalls int
+	lazy := LazyFunc(func() interface{} {
+		lazyCalls++
+		return "computed"
+	})
+	lg.WithField("v", lazy).Debug("not logged")
+	lg.WithField("v", lazy).Warn("logged")
+	lg.Close()
+
+	if calls != 1 {
+		t.Errorf("expected closure to be called once, called %d times", calls)
+	}
+	if lazyCalls != 1 {
+		t.Errorf("expected lazy value to be computed once, computed %d times", lazyCalls)
+	}
+	out := buf.String()
+	if !strings.Contains(out, " INFO expensive") || !strings.Contains(out, "(a=1)") ||
+		!strings.Contains(out, "(n=1)") {
+		t.Errorf("missing closure entry or fields: %q", out)
+	}
+	if !strings.Contains(out, " WARN logged (v=computed)") {
+		t.Errorf("lazy value not computed: %q", out)
+	}
+}
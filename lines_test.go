@@ -0,0 +1,28 @@
+package alog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// outputLines returns the lines written to buf.
+func outputLines(buf fmt.Stringer) []string {
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// checkLines checks that the lines written to buf start with the expected
+// lines, one for each line, and returns the lines.
+func checkLines(t *testing.T, buf fmt.Stringer, expect ...string) []string {
+	t.Helper()
+	lines := outputLines(buf)
+	if len(lines) != len(expect) {
+		t.Fatalf("expected %d lines, got %d: %q", len(expect), len(lines), lines)
+	}
+	for i := range expect {
+		if !strings.HasPrefix(lines[i], expect[i]) {
+			t.Errorf("expected line %q, got %q", expect[i], lines[i])
+		}
+	}
+	return lines
+}
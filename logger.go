@@ -21,21 +21,59 @@ https://dave.cheney.net/2015/11/05/lets-talk-about-logging
 
 Using alog requires creating a logger instance.  There is no default logger
 since the asynchronous logging requires a separate goroutine.
-
 */
 package alog
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Fields map[string]interface{}
 
+// LazyValue is a field value that is computed only when an entry having the
+// field is written.  The LogValue method is called by the goroutine that
+// writes log entries, so it must be safe to call concurrently with the code
+// that logged the entry.
+type LazyValue interface {
+	LogValue() interface{}
+}
+
+// LazyFunc is a function that implements LazyValue by returning its result.
+type LazyFunc func() interface{}
+
+// LogValue calls the function and returns its result.
+func (f LazyFunc) LogValue() interface{} { return f() }
+
+// resolveFields returns fields with any LazyValue computed.  If there are no
+// lazy values, fields is returned unchanged.
+func resolveFields(fields Fields) Fields {
+	var resolved Fields
+	for k, v := range fields {
+		lv, ok := v.(LazyValue)
+		if !ok {
+			continue
+		}
+		if resolved == nil {
+			resolved = make(Fields, len(fields))
+			for k, v := range fields {
+				resolved[k] = v
+			}
+		}
+		resolved[k] = lv.LogValue()
+	}
+	if resolved == nil {
+		return fields
+	}
+	return resolved
+}
+
 type Logger interface {
 	// Print, Println, and Printf log a message without a level label and
 	// without regard to any configured log level, if using leveled logging.
@@ -91,6 +129,44 @@ type Logger interface {
 	Debugln(v ...interface{})
 	Debugf(format string, v ...interface{})
 
+	// Trace, Traceln, and Tracef log a message at TraceLevel.  Arguments are
+	// handled in the manner of fmt.Print, fmt.Println, and fmt.Printf
+	// respectively.
+	Trace(v ...interface{})
+	Traceln(v ...interface{})
+	Tracef(format string, v ...interface{})
+
+	// Log and Logf log a message at any level, including levels registered
+	// with RegisterLevel.  Arguments are handled in the manner of fmt.Print
+	// and fmt.Printf respectively.  Log and Logf do not call panic() or
+	// os.Exit(), even at PanicLevel or FatalLevel.
+	Log(level Level, v ...interface{})
+	Logf(level Level, format string, v ...interface{})
+
+	// ErrorFn, WarnFn, InfoFn, DebugFn, and TraceFn log the message and fields
+	// returned by fn at their respective levels.  The function is only
+	// called if the Logger is enabled at the level, so that an expensive
+	// message is not built for an entry that is not logged.  Any fields
+	// returned are added to the Logger's fields.
+	ErrorFn(fn func() (string, Fields))
+	WarnFn(fn func() (string, Fields))
+	InfoFn(fn func() (string, Fields))
+	DebugFn(fn func() (string, Fields))
+	TraceFn(fn func() (string, Fields))
+
+	// ErrorContext, WarnContext, InfoContext, DebugContext, and TraceContext
+	// log a message at their respective levels, with the fields that the
+	// functions registered by RegisterContextExtractor get from ctx.
+	// Arguments are handled in the manner of fmt.Print.
+	ErrorContext(ctx context.Context, v ...interface{})
+	WarnContext(ctx context.Context, v ...interface{})
+	InfoContext(ctx context.Context, v ...interface{})
+	DebugContext(ctx context.Context, v ...interface{})
+	TraceContext(ctx context.Context, v ...interface{})
+
+	// Enabled reports whether entries at the given level are logged.
+	Enabled(level Level) bool
+
 	// WithFields creates a wrapper for the Logger that outputs each log
 	// message with the specified fields included as part of the message.
 	WithFields(fields Fields) Logger
@@ -98,6 +174,83 @@ type Logger interface {
 	// WithField calls WithField for a single entry.
 	WithField(key string, value interface{}) Logger
 
+	// WithError calls WithField with ErrorField as the key and err as the
+	// value.
+	WithError(err error) Logger
+
+	// Named returns a Logger that logs with the NameField field set to name.
+	// Names of Loggers created from a named Logger are joined with ".", so
+	// that lg.Named("db").Named("pool") logs with the field logger=db.pool.
+	// The name selects the level set for it by SetNameLevels.
+	Named(name string) Logger
+
+	// SetNameLevels sets the levels of named Loggers by name prefix.  A name
+	// prefix is made of whole dot-separated components, so that the level set
+	// for "db" applies to the Loggers named "db" and "db.pool", unless "db.pool"
+	// has its own level.  A Logger whose name has no level set logs at the
+	// level of the root Logger.  A name set to NoLevel logs nothing.  The
+	// levels apply to the root Logger and all Loggers created from it, and may
+	// be changed at any time.  If leveled logging is disabled, names have no
+	// effect.
+	SetNameLevels(levels map[string]Level)
+
+	// V returns the Logger if V-logging at the given verbosity is enabled, in
+	// the manner of glog.  Otherwise, it returns a Logger that logs nothing.
+	// V-logging is enabled if level is not greater than the global verbosity
+	// set by SetVerbosity, or than the verbosity that SetVModule sets for the
+	// source file that calls V.  The decision for each callsite is cached, so
+	// that V costs only an atomic load when no vmodule patterns are set, or
+	// when V is enabled by the global verbosity.
+	V(level int) Logger
+
+	// Once returns a Logger that only logs the first entry logged through
+	// any Logger returned by Once with the same key.  If key is empty, the
+	// first entry logged from each callsite is logged.  The 4096 most
+	// recently used keys are remembered; a key that is forgotten logs its
+	// next entry again.
+	Once(key string) Logger
+
+	// EveryN returns a Logger that logs the first entry, and then every n-th
+	// entry, logged from each callsite.
+	EveryN(n int) Logger
+
+	// Every returns a Logger that logs at most one entry per interval from
+	// each callsite.
+	Every(interval time.Duration) Logger
+
+	// RateLimited returns a Logger that limits the entries logged from each
+	// callsite to rate entries per second, with bursts of up to burst
+	// entries.
+	RateLimited(rate float64, burst int) Logger
+
+	// Writer returns an io.Writer that logs each line written to it as a
+	// separate entry at the given level.  Use NoLevel to log lines in the
+	// manner of Print.  Lines are logged without calling panic() or
+	// os.Exit(), even at PanicLevel or FatalLevel.  Closing the Writer logs
+	// any last line that does not end with a newline.
+	Writer(level Level, options ...WriterOption) io.WriteCloser
+
+	// SetFilter sets a Filter that entries must match to be written.  There
+	// is one Filter for the root Logger and all Loggers created from it, such
+	// as by WithFields or Named, so calling SetFilter on any of them sets the
+	// Filter for all of them.  The Filter may be changed at any time.  Entries
+	// are filtered by level before being matched against the Filter.  A nil
+	// Filter removes filtering.
+	SetFilter(filter *Filter)
+
+	// Recover and RecoverAndContinue must be called directly by a deferred
+	// function call, as in "defer lg.Recover()".  If the goroutine is
+	// panicking, they stop the panic, log the panic value at PanicLevel with
+	// the StackField field set to the goroutine's stack, and call Flush.
+	// Recover then panics again with the same value, and RecoverAndContinue
+	// lets the function that deferred it return normally.
+	Recover()
+	RecoverAndContinue()
+
+	// Flush waits for all entries logged before the call to be written to the
+	// sinks.
+	Flush()
+
 	// Close stops asynchronous logging and waits for any unwritten entries to
 	// be written to the io.Writer.  This does not close the log's io.Writer,
 	// and doing so it the caller's responsibility.  Do not call Close() while
@@ -105,7 +258,30 @@ type Logger interface {
 	Close()
 }
 
-// New creates a new Logger instance that outputs log entries as text.
+// New creates a new Logger instance that uses the given Formatter to format
+// log entries.
+//
+// The out variable sets the destination to which log data is written.  Each
+// formatted entry is written to out with a single call to its Write method.
+//
+// Set level to NoLevel to choose not to do leveled logging.  Otherwise, set to
+// the severity level to log at.
+//
+// Any options are applied to configure additional Logger behavior.
+func New(out io.Writer, level Level, formatter Formatter, options ...Option) Logger {
+	a := newLogger(level, options)
+	// The Logger filters entries by level, including those of named Loggers
+	// that have their own level, so the sink writes all entries it is sent.
+	sinkLevel := a.level
+	if sinkLevel != NoLevel {
+		sinkLevel = loadLevels().max
+	}
+	a.sinks = []*sinkQueue{newSinkQueue(NewSink(out, formatter), sinkLevel, 0)}
+	go a.run()
+	return a
+}
+
+// NewText creates a new Logger instance that outputs log entries as text.
 //
 // The out variable sets the destination to which log data is written.
 //
@@ -116,12 +292,10 @@ type Logger interface {
 // not specified, defaults to "Jan 02 15:04:05".  To disable timestamp output,
 // specify a TimeLayout string consisting on one or more spaces. The prefix
 // appears at the beginning of each generated log line.
-func NewText(out io.Writer, level Level, timeLayout, prefix string) Logger {
-	a := newLogger(out, level, timeLayout)
-	a.writeFunc = a.writeText
-	a.prefix = prefix
-	go a.run()
-	return a
+//
+// Any options are applied to configure additional Logger behavior.
+func NewText(out io.Writer, level Level, timeLayout, prefix string, options ...Option) Logger {
+	return New(out, level, NewTextFormatter(timeLayout, prefix), options...)
 }
 
 // NewJSON creates a new Logger instance that outputs log entries as JSON.
@@ -134,47 +308,38 @@ func NewText(out io.Writer, level Level, timeLayout, prefix string) Logger {
 // The timeLayout defines the timestamp format according to time.Format.  If
 // not specified, defaults to "Jan 02 15:04:05".  To disable timestamp output,
 // specify a TimeLayout string consisting on one or more spaces.
-func NewJSON(out io.Writer, level Level, timeLayout string) Logger {
-	a := newLogger(out, level, timeLayout)
-	a.writeFunc = a.writeJSON
-	go a.run()
-	return a
+//
+// Any options are applied to configure additional Logger behavior.
+func NewJSON(out io.Writer, level Level, timeLayout string, options ...Option) Logger {
+	return New(out, level, NewJSONFormatter(timeLayout), options...)
 }
 
-const (
-	// ErrorField is exported so it can be used explicitly as a field name.
-	ErrorField = "error"
-
-	levelField    = "level"
-	extLevelField = "fields.level"
-	msgField      = "msg"
-	extMsgField   = "fields.msg"
-	timeField     = "time"
-	extTimeField  = "fields.time"
-)
-
-const defaultTimeLayout = "Jan 02 15:04:05"
-
-func newLogger(out io.Writer, level Level, timeLayout string) *logger {
-	if out == nil {
-		out = os.Stdout
-	}
-	if timeLayout == "" {
-		timeLayout = defaultTimeLayout
-	} else {
-		timeLayout = strings.TrimSpace(timeLayout)
-	}
-	if level < NoLevel {
-		level = NoLevel
-	} else if level > DebugLevel {
-		level = DebugLevel
+// Option is a function that configures optional Logger behavior.  Options are
+// passed to New, NewText, and NewJSON.
+type Option func(*logger)
+
+// ErrorHandler returns an Option that sets a function to call with any error
+// that occurs while writing a log entry, such as an error returned from the
+// io.Writer.  The handler is called by the goroutine that writes log entries,
+// so it must not log to the same Logger.  For a Logger created by NewMulti,
+// the handler may be called concurrently by the goroutines of different
+// sinks.  Without a handler, errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(a *logger) {
+		a.errFunc = handler
 	}
+}
+
+func newLogger(level Level, options []Option) *logger {
 	a := &logger{
-		out:      out,
 		entChan:  make(chan *entry, 64),
 		doneChan: make(chan struct{}),
-		level:    level,
-		tsLayout: timeLayout,
+		level:    clampLevel(level),
+	}
+	a.off = &fieldLogger{logger: a, off: true}
+	a.root = &fieldLogger{logger: a}
+	for _, opt := range options {
+		opt(a)
 	}
 	return a
 }
@@ -187,17 +352,45 @@ type entry struct {
 	args   []interface{}
 	fields Fields
 	ln     bool
+	// pc is the program counter of the log call.  It is only set for entries
+	// logged by a Logger that has a gate.
+	pc uintptr
+	// flushed, if not nil, marks an entry that is not logged, but is closed
+	// once the entries before it are written to the sinks.
+	flushed chan struct{}
+}
+
+// message formats the entry's arguments into the log message.
+func (ent *entry) message() string {
+	if ent.format != "" {
+		return fmt.Sprintf(ent.format, ent.args...)
+	}
+	if ent.ln {
+		msg := fmt.Sprintln(ent.args...)
+		return msg[:len(msg)-1]
+	}
+	return fmt.Sprint(ent.args...)
 }
 
 type logger struct {
-	buf       []byte
-	entChan   chan *entry
-	doneChan  chan struct{}
-	writeFunc func(*entry)
-	out       io.Writer
-	level     Level
-	tsLayout  string
-	prefix    string
+	entChan  chan *entry
+	doneChan chan struct{}
+	sinks    []*sinkQueue
+	filter   filterValue
+	sites    sync.Map
+	onceKeys onceKeys
+	errFunc  func(error)
+	level    Level
+
+	nameLevels  nameLevels
+	dedupWindow time.Duration
+
+	// off is returned by V when V-logging is disabled.
+	off *fieldLogger
+	// root logs for the Debug methods, so that they find their callsite in
+	// the same way as a fieldLogger.
+	root       *fieldLogger
+	debugSites *DebugSites
 }
 
 func (a *logger) Print(v ...interface{}) {
@@ -227,238 +420,398 @@ func (a *logger) WithError(err error) Logger {
 	return a.WithFields(Fields{ErrorField: err})
 }
 
+func (a *logger) Once(key string) Logger {
+	return withGate(a, a.onceGate(key))
+}
+
+func (a *logger) EveryN(n int) Logger {
+	return withGate(a, a.everyNGate(n))
+}
+
+func (a *logger) Every(interval time.Duration) Logger {
+	return withGate(a, a.everyGate(interval))
+}
+
+func (a *logger) RateLimited(rate float64, burst int) Logger {
+	return withGate(a, a.rateLimitGate(rate, burst))
+}
+
+func (a *logger) SetFilter(filter *Filter) {
+	a.filter.store(filter)
+}
+
+func (a *logger) Flush() {
+	done := make(chan struct{})
+	a.entChan <- &entry{flushed: done}
+	<-done
+}
+
 func (a *logger) Close() {
 	close(a.entChan)
 	<-a.doneChan
 }
 
 func (a *logger) run() {
-	for ent := range a.entChan {
-		a.writeFunc(ent)
+	for _, sq := range a.sinks {
+		sq.start(a.handleError)
 	}
-	close(a.doneChan)
-}
-
-func (a *logger) writeText(ent *entry) {
-	a.buf = a.buf[:0]
-	if a.prefix != "" {
-		a.buf = append(a.buf, a.prefix...)
+	var dedup *deduper
+	var expired <-chan time.Time
+	if a.dedupWindow > 0 {
+		dedup = newDeduper(a.dedupWindow)
+		expired = dedup.timer.C
 	}
-	if a.tsLayout != "" {
-		a.buf = append(a.buf, ent.ts.Format(a.tsLayout)...)
-	}
-	if a.level != NoLevel && ent.level != NoLevel {
-		a.buf = append(a.buf, levelNamesText[int(ent.level)]...)
-	} else {
-		a.buf = append(a.buf, ' ')
+loop:
+	for {
+		select {
+		case ent, ok := <-a.entChan:
+			if !ok {
+				break loop
+			}
+			if ent.flushed != nil {
+				// Write the summary of any repeats, so that Flush writes
+				// all entries logged before it.
+				if dedup != nil {
+					a.send(dedup.reset(nil))
+				}
+				for _, sq := range a.sinks {
+					sq.flush()
+				}
+				close(ent.flushed)
+				continue
+			}
+			e := &Entry{
+				Time:    ent.ts,
+				Level:   ent.level,
+				Message: ent.message(),
+				Fields:  resolveFields(ent.fields),
+			}
+			if !a.filter.load().Match(e) {
+				continue
+			}
+			if dedup != nil {
+				if dedup.repeat(e) {
+					continue
+				}
+				a.send(dedup.reset(e))
+			}
+			a.send(e)
+		case <-expired:
+			a.send(dedup.expired())
+		}
 	}
-	if ent.format != "" {
-		a.buf = append(a.buf, fmt.Sprintf(ent.format, ent.args...)...)
-	} else if ent.ln {
-		a.buf = append(a.buf, fmt.Sprintln(ent.args...)...)
-		a.buf = a.buf[:len(a.buf)-1]
-	} else {
-		a.buf = append(a.buf, fmt.Sprint(ent.args...)...)
+	if dedup != nil {
+		a.send(dedup.reset(nil))
 	}
-	for k, v := range ent.fields {
-		a.buf = append(a.buf, " ("...)
-		a.buf = append(a.buf, k...)
-		a.buf = append(a.buf, '=')
-		a.buf = append(a.buf, fmt.Sprint(v)...)
-		a.buf = append(a.buf, ')')
+	for _, sq := range a.sinks {
+		sq.stop()
 	}
-
-	a.buf = append(a.buf, '\n')
-	a.out.Write(a.buf)
+	close(a.doneChan)
 }
 
-func (a *logger) writeJSON(ent *entry) {
-	if ent.fields == nil {
-		ent.fields = make(map[string]interface{}, 3)
-	}
-	// Convert any error types to string.
-	for k, v := range ent.fields {
-		if err, ok := v.(error); ok {
-			ent.fields[k] = err.Error()
-		}
-	}
-	if a.tsLayout != "" {
-		if v, ok := ent.fields[timeField]; ok {
-			ent.fields[extTimeField] = v
-		}
-		ent.fields[timeField] = ent.ts.Format(a.tsLayout)
+// send sends an entry to all of the sinks.  Nil entries are ignored.
+func (a *logger) send(e *Entry) {
+	if e == nil {
+		return
 	}
-	if a.level != NoLevel && ent.level != NoLevel {
-		if v, ok := ent.fields[levelField]; ok {
-			ent.fields[extLevelField] = v
-		}
-		ent.fields[levelField] = ent.level.String()
+	for _, sq := range a.sinks {
+		sq.send(e)
 	}
-	if ent.format != "" {
-		if v, ok := ent.fields[msgField]; ok {
-			ent.fields[extMsgField] = v
-		}
-		ent.fields[msgField] = fmt.Sprintf(ent.format, ent.args...)
-	} else {
-		if v, ok := ent.fields[msgField]; ok {
-			ent.fields[extMsgField] = v
-		}
-		ent.fields[msgField] = fmt.Sprint(ent.args...)
-	}
-	encoded, err := json.Marshal(ent.fields)
-	if err != nil {
-		fmt.Println("Failed to marshal fields to JSON:", err)
+}
+
+// errorOutput is where errors are written for a Logger that has no error
+// handler.
+var errorOutput io.Writer = os.Stderr
+
+// handleError passes an error, that occurred while writing a log entry, to
+// the error handler, or writes it to errorOutput if there is no handler.
+func (a *logger) handleError(err error) {
+	if a.errFunc != nil {
+		a.errFunc(err)
 		return
 	}
-	a.out.Write(append(encoded, '\n'))
+	fmt.Fprintln(errorOutput, "alog:", err)
 }
 
 // ---- Leveled log functions -----
 
-// Log severity levels
+// Log severity levels.  Lower values are more severe.  The values are spaced
+// apart so that custom levels, registered with RegisterLevel, can be placed
+// between them.  The values 1 through 6, which were the values of PanicLevel
+// through DebugLevel before the levels were spaced apart, are reserved, and
+// are treated as the levels they used to be.
 const (
 	// Leveled logging is disabled.  Messages are not filtered by level, and no
 	// level label or field appears in the log messages.  This is the default
 	// behavior.
-	NoLevel Level = iota
+	NoLevel Level = 0
 
 	// PanicLevel is most severe level; it logs a message and calls panic.
 	// This severity level indicates an unrecoverable condition caused by a
 	// defect in programming logic or other situation not allowed by the
 	// system or program.
-	PanicLevel
+	PanicLevel Level = 10
 
 	// FatalLevel logs and then calls os.Exit(1).  This severity level
 	// indicates an unrecoverable condition that requires the termination of
 	// the program.
-	FatalLevel
+	FatalLevel Level = 20
 
 	// ErrorLevel is used for error conditions or failures, usually
 	// sufficiently critical to prevent the program from executing one or more
 	// intended tasks.
-	ErrorLevel
+	ErrorLevel Level = 30
 
 	// WarnLevel indicates undesirable conditions that should not normally
 	// occur during proper execution with ideal configuration, but that are not
 	// critical enough to stop the program from executing intended tasks.
-	WarnLevel
+	WarnLevel Level = 40
 
 	// InfoLevel indicated data that is informative, but its record is not
 	// crucial under normal conditions.
-	InfoLevel
+	InfoLevel Level = 50
 
 	// DebugLevel labels detailed information the is intended for debugging
 	// program logic or configuration.
-	DebugLevel
+	DebugLevel Level = 60
+
+	// TraceLevel labels information that is more detailed than DebugLevel,
+	// such as the steps taken by program logic.
+	TraceLevel Level = 70
 )
 
 // Level is the severity value for log entries.
 type Level int
 
-var levelNames = [DebugLevel + 1]string{
-	"", "panic", "fatal", "error", "warn", "info", "debug"}
+// levelTable holds the names of the registered levels.  It is replaced, not
+// modified, when a level is registered.
+type levelTable struct {
+	names map[Level]string
+	text  map[Level]string
+	max   Level
+}
+
+var (
+	levelsMutex sync.Mutex
+	levels      atomic.Value
+)
+
+func init() {
+	t := &levelTable{
+		names: make(map[Level]string),
+		text:  make(map[Level]string),
+	}
+	for level, name := range map[Level]string{
+		PanicLevel: "panic",
+		FatalLevel: "fatal",
+		ErrorLevel: "error",
+		WarnLevel:  "warn",
+		InfoLevel:  "info",
+		DebugLevel: "debug",
+		TraceLevel: "trace",
+	} {
+		t.add(level, name)
+	}
+	levels.Store(t)
+}
+
+func (t *levelTable) add(level Level, name string) {
+	t.names[level] = name
+	t.text[level] = " " + strings.ToUpper(name) + " "
+	if level > t.max {
+		t.max = level
+	}
+}
+
+func loadLevels() *levelTable {
+	return levels.Load().(*levelTable)
+}
+
+// RegisterLevel registers a custom level with the given name.  The level's
+// value determines its severity: it is more severe than the levels with
+// greater values, and less severe than those with lesser values.  For
+// example, a level between WarnLevel and InfoLevel:
+//
+//	const NoticeLevel = alog.Level(45)
+//
+//	func init() {
+//		if err := alog.RegisterLevel(NoticeLevel, "notice"); err != nil {
+//			panic(err)
+//		}
+//	}
+//
+// Entries at a custom level are logged with Log or Logf.  The level must be
+// greater than PanicLevel, and neither the level nor its name may already be
+// registered.  Names are not case sensitive.  Register levels before creating
+// any Logger that uses them.
+func RegisterLevel(level Level, name string) error {
+	name = strings.ToLower(name)
+	if level <= PanicLevel {
+		return fmt.Errorf("invalid log level value %d", int(level))
+	}
+	if name == "" || name == "none" {
+		return fmt.Errorf("invalid log level name %q", name)
+	}
+
+	levelsMutex.Lock()
+	defer levelsMutex.Unlock()
+	old := loadLevels()
+	if existing, ok := old.names[level]; ok {
+		return fmt.Errorf("log level %d already registered as %q", int(level), existing)
+	}
+	t := &levelTable{
+		names: make(map[Level]string, len(old.names)+1),
+		text:  make(map[Level]string, len(old.text)+1),
+	}
+	for l, n := range old.names {
+		if n == name {
+			return fmt.Errorf("log level %q already registered", name)
+		}
+		t.add(l, n)
+	}
+	t.add(level, name)
+	levels.Store(t)
+	return nil
+}
+
+// legacyLevel maps the reserved values 1 through 6 to the levels PanicLevel
+// through DebugLevel, that had those values before the levels were spaced
+// apart, so that stored numeric levels keep their meaning.  Every exported
+// function and method that takes a Level maps it with legacyLevel.
+func legacyLevel(level Level) Level {
+	if level >= 1 && level <= 6 {
+		return level * 10
+	}
+	return level
+}
+
+// clampLevel limits a level to the range of registered levels, after mapping
+// any legacy value.
+func clampLevel(level Level) Level {
+	level = legacyLevel(level)
+	if level < NoLevel {
+		return NoLevel
+	}
+	if max := loadLevels().max; level > max {
+		return max
+	}
+	return level
+}
 
 // String converts a Level value to a string containing the name of the level.
-func (lvl Level) String() string { return levelNames[int(lvl)] }
+// Levels that are not registered are formatted as "level(N)".
+func (lvl Level) String() string {
+	lvl = legacyLevel(lvl)
+	if lvl == NoLevel {
+		return ""
+	}
+	if name, ok := loadLevels().names[lvl]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(lvl))
+}
+
+// text returns the label of the level used by the text formatter.
+func (lvl Level) text() string {
+	lvl = legacyLevel(lvl)
+	if text, ok := loadLevels().text[lvl]; ok {
+		return text
+	}
+	return " " + strings.ToUpper(lvl.String()) + " "
+}
 
-var levelNamesText = [DebugLevel + 1]string{
-	"", " PANIC ", " FATAL ", " ERROR ", " WARN ", " INFO ", " DEBUG "}
+// ParseLevel returns the Level that has the given name, as returned by
+// Level.String.  The name "none" is also accepted for NoLevel.
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(name)
+	if name == "none" {
+		return NoLevel, nil
+	}
+	for level, n := range loadLevels().names {
+		if n == name {
+			return level, nil
+		}
+	}
+	return NoLevel, fmt.Errorf("unknown log level %q", name)
+}
 
 func (a *logger) Panic(v ...interface{}) {
-	a.log(nil, PanicLevel, v)
+	a.root.emit(PanicLevel, "", v, false)
 	a.Close()
 	panic(fmt.Sprint(v...))
 }
 func (a *logger) Panicln(v ...interface{}) {
-	a.logln(nil, PanicLevel, v)
+	a.root.emit(PanicLevel, "", v, true)
 	a.Close()
 	panic(fmt.Sprint(v...))
 }
 func (a *logger) Panicf(format string, v ...interface{}) {
-	a.logf(nil, PanicLevel, format, v)
+	a.root.emit(PanicLevel, format, v, false)
 	a.Close()
 	panic(fmt.Sprintf(format, v...))
 }
 
 func (a *logger) Fatal(v ...interface{}) {
-	a.log(nil, FatalLevel, v)
+	a.root.emit(FatalLevel, "", v, false)
 	a.Close()
 	os.Exit(1)
 }
 func (a *logger) Fatalln(v ...interface{}) {
-	a.logln(nil, FatalLevel, v)
+	a.root.emit(FatalLevel, "", v, true)
 	a.Close()
 	os.Exit(1)
 }
 func (a *logger) Fatalf(format string, v ...interface{}) {
-	a.logf(nil, FatalLevel, format, v)
+	a.root.emit(FatalLevel, format, v, false)
 	a.Close()
 	os.Exit(1)
 }
 
-func (a *logger) Error(v ...interface{})   { a.log(nil, ErrorLevel, v) }
-func (a *logger) Errorln(v ...interface{}) { a.logln(nil, ErrorLevel, v) }
+func (a *logger) Error(v ...interface{})   { a.root.emit(ErrorLevel, "", v, false) }
+func (a *logger) Errorln(v ...interface{}) { a.root.emit(ErrorLevel, "", v, true) }
 func (a *logger) Errorf(format string, v ...interface{}) {
-	a.logf(nil, ErrorLevel, format, v)
+	a.root.emit(ErrorLevel, format, v, false)
 }
 
-func (a *logger) Warn(v ...interface{})   { a.log(nil, WarnLevel, v) }
-func (a *logger) Warnln(v ...interface{}) { a.logln(nil, WarnLevel, v) }
+func (a *logger) Warn(v ...interface{})   { a.root.emit(WarnLevel, "", v, false) }
+func (a *logger) Warnln(v ...interface{}) { a.root.emit(WarnLevel, "", v, true) }
 func (a *logger) Warnf(format string, v ...interface{}) {
-	a.logf(nil, WarnLevel, format, v)
+	a.root.emit(WarnLevel, format, v, false)
 }
 
-func (a *logger) Info(v ...interface{})   { a.log(nil, InfoLevel, v) }
-func (a *logger) Infoln(v ...interface{}) { a.logln(nil, InfoLevel, v) }
+func (a *logger) Info(v ...interface{})   { a.root.emit(InfoLevel, "", v, false) }
+func (a *logger) Infoln(v ...interface{}) { a.root.emit(InfoLevel, "", v, true) }
 func (a *logger) Infof(format string, v ...interface{}) {
-	a.logf(nil, InfoLevel, format, v)
+	a.root.emit(InfoLevel, format, v, false)
 }
 
-func (a *logger) Debug(v ...interface{})   { a.log(nil, DebugLevel, v) }
-func (a *logger) Debugln(v ...interface{}) { a.logln(nil, DebugLevel, v) }
+func (a *logger) Debug(v ...interface{})   { a.root.emit(DebugLevel, "", v, false) }
+func (a *logger) Debugln(v ...interface{}) { a.root.emit(DebugLevel, "", v, true) }
 func (a *logger) Debugf(format string, v ...interface{}) {
-	a.logf(nil, DebugLevel, format, v)
+	a.root.emit(DebugLevel, format, v, false)
 }
 
-func (a *logger) log(fields Fields, level Level, v []interface{}) {
-	if !a.LogableAt(level) {
-		return
-	}
-	a.entChan <- &entry{
-		ts:     time.Now(),
-		level:  level,
-		args:   v,
-		fields: fields,
-	}
+func (a *logger) Trace(v ...interface{})   { a.root.emit(TraceLevel, "", v, false) }
+func (a *logger) Traceln(v ...interface{}) { a.root.emit(TraceLevel, "", v, true) }
+func (a *logger) Tracef(format string, v ...interface{}) {
+	a.root.emit(TraceLevel, format, v, false)
 }
-func (a *logger) logln(fields Fields, level Level, v []interface{}) {
-	if !a.LogableAt(level) {
-		return
-	}
-	a.entChan <- &entry{
-		ts:     time.Now(),
-		level:  level,
-		args:   v,
-		fields: fields,
-		ln:     true,
-	}
+
+func (a *logger) Log(level Level, v ...interface{}) { a.root.emit(legacyLevel(level), "", v, false) }
+func (a *logger) Logf(level Level, format string, v ...interface{}) {
+	a.root.emit(legacyLevel(level), format, v, false)
 }
-func (a *logger) logf(fields Fields, level Level, format string, v []interface{}) {
-	if !a.LogableAt(level) {
-		return
-	}
-	a.entChan <- &entry{
-		ts:     time.Now(),
-		level:  level,
-		format: format,
-		args:   v,
-		fields: fields,
-	}
+
+func (a *logger) ErrorFn(fn func() (string, Fields)) { a.root.emitFn(ErrorLevel, fn) }
+func (a *logger) WarnFn(fn func() (string, Fields))  { a.root.emitFn(WarnLevel, fn) }
+func (a *logger) InfoFn(fn func() (string, Fields))  { a.root.emitFn(InfoLevel, fn) }
+func (a *logger) DebugFn(fn func() (string, Fields)) { a.root.emitFn(DebugLevel, fn) }
+func (a *logger) TraceFn(fn func() (string, Fields)) { a.root.emitFn(TraceLevel, fn) }
+
+func (a *logger) Enabled(level Level) bool {
+	return a.LogableAt(level)
 }
 
 func (a *logger) LogableAt(level Level) bool {
-	if a.level != NoLevel && a.level < level {
-		return false
-	}
-	return true
+	return a.logableNamed("", level)
 }
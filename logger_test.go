@@ -3,6 +3,7 @@ package alog
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -81,3 +82,142 @@ func TestFields(t *testing.T) {
 		t.Fatal("message should not contain fileds")
 	}
 }
+
+// restoreLevels restores the registered levels when the test ends.
+func restoreLevels(t *testing.T) {
+	old := loadLevels()
+	t.Cleanup(func() {
+		levelsMutex.Lock()
+		levels.Store(old)
+		levelsMutex.Unlock()
+	})
+}
+
+func TestCustomLevels(t *testing.T) {
+	restoreLevels(t)
+	const noticeLevel = Level(45)
+	if err := RegisterLevel(noticeLevel, "Notice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterLevel(noticeLevel, "other"); err == nil {
+		t.Error("expected error registering level value twice")
+	}
+	if err := RegisterLevel(Level(46), "info"); err == nil {
+		t.Error("expected error registering level name twice")
+	}
+	if err := RegisterLevel(Level(5), "reserved"); err == nil {
+		t.Error("expected error registering reserved level value")
+	}
+	if lvl, err := ParseLevel("NOTICE"); err != nil || lvl != noticeLevel {
+		t.Error("failed to parse custom level:", lvl, err)
+	}
+	if noticeLevel.String() != "notice" || Level(99).String() != "level(99)" {
+		t.Error("bad level names")
+	}
+
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, noticeLevel, "", "")
+	lg.Log(noticeLevel, "at notice")
+	lg.Logf(WarnLevel, "at %s", "warn")
+	lg.Info("at info")
+	lg.Trace("at trace")
+	lg.Close()
+
+	s := buf.String()
+	if !strings.Contains(s, " NOTICE at notice") || !strings.Contains(s, " WARN at warn") {
+		t.Error("missing log entries:", s)
+	}
+	if strings.Contains(s, "at info") || strings.Contains(s, "at trace") {
+		t.Error("entries below custom level logged:", s)
+	}
+
+	buf.Reset()
+	lg = NewText(buf, Level(1000), "", "")
+	lg.Tracef("at %s", "trace")
+	lg.Close()
+	if !strings.Contains(buf.String(), " TRACE at trace") {
+		t.Error("missing trace entry:", buf.String())
+	}
+
+	// Legacy level values are mapped to the levels they used to be.
+	buf.Reset()
+	lg = NewText(buf, Level(5), "", "")
+	lg.Log(Level(3), "legacy error")
+	lg.Info("info")
+	lg.Debug("debug")
+	if !lg.Enabled(Level(5)) || lg.Enabled(Level(6)) {
+		t.Error("wrong legacy levels enabled")
+	}
+	lg.Close()
+	s = buf.String()
+	if !strings.Contains(s, " ERROR legacy error") || !strings.Contains(s, " INFO info") || strings.Contains(s, "debug") {
+		t.Errorf("wrong legacy level output: %q", s)
+	}
+}
+
+// TestLegacyLevelAPIs checks that each exported function and method that
+// takes a Level treats the values 1 through 6 as the levels they used to be.
+func TestLegacyLevelAPIs(t *testing.T) {
+	legacyError := Level(3)
+	if legacyError.String() != "error" {
+		t.Error("wrong name of legacy level:", legacyError.String())
+	}
+	if sev := SyslogSeverity(legacyError); sev != sevErr {
+		t.Error("wrong syslog severity of legacy level:", sev)
+	}
+	atLeast := LevelAtLeast(Level(4))
+	if !atLeast(&Entry{Level: ErrorLevel}) || atLeast(&Entry{Level: InfoLevel}) {
+		t.Error("wrong LevelAtLeast with legacy level")
+	}
+
+	buf := new(bytes.Buffer)
+	sinkBuf := new(bytes.Buffer)
+	lg := NewMulti([]SinkConfig{
+		{Sink: NewSink(buf, NewTextFormatter(" ", "")), Level: Level(6)},
+		{Sink: NewSink(sinkBuf, NewTextFormatter(" ", "")), Level: Level(4)},
+	})
+	lg.SetNameLevels(map[string]Level{"db": Level(3)})
+	db := lg.Named("db")
+	if !lg.(*logger).LogableAt(Level(6)) || !db.(*fieldLogger).LogableAt(legacyError) || db.(*fieldLogger).LogableAt(Level(4)) {
+		t.Error("wrong LogableAt with legacy levels")
+	}
+	if !db.Enabled(legacyError) || db.Enabled(Level(5)) {
+		t.Error("wrong Enabled with legacy levels")
+	}
+	lg.Log(Level(5), "log")
+	lg.Logf(legacyError, "logf")
+	db.Log(Level(4), "db warn")
+	lg.Writer(Level(4)).Write([]byte("writer\n"))
+	NewStdLog(lg, Level(6)).Print("stdlog")
+	lg.Close()
+
+	expect := []string{
+		" INFO log",
+		" ERROR logf",
+		" WARN writer",
+		" DEBUG stdlog",
+	}
+	checkLines(t, buf, expect...)
+	if s := sinkBuf.String(); s != " ERROR logf\n WARN writer\n" {
+		t.Errorf("wrong output of sink with legacy level: %q", s)
+	}
+}
+
+func TestErrorOutput(t *testing.T) {
+	errBuf := new(bytes.Buffer)
+	errorOutput = errBuf
+	defer func() { errorOutput = os.Stderr }()
+
+	buf := new(bytes.Buffer)
+	lg := NewJSON(buf, InfoLevel, "")
+	lg.WithField("fn", func() {}).Info("cannot marshal")
+	lg.Info("can marshal")
+	lg.Close()
+
+	if !strings.HasPrefix(errBuf.String(), "alog: failed to marshal fields to JSON:") {
+		t.Errorf("error not written to error output: %q", errBuf.String())
+	}
+	if strings.Contains(buf.String(), "cannot marshal") || !strings.Contains(buf.String(), "can marshal") {
+		t.Errorf("wrong output: %q", buf.String())
+	}
+}
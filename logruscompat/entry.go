@@ -0,0 +1,184 @@
+package logruscompat
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gammazero/alog"
+)
+
+// Entry is an intermediate logging entry that holds fields, in the manner of
+// logrus.Entry.  Entries are created by the Logger's With functions, and are
+// passed to hooks when logged.
+type Entry struct {
+	Logger *Logger
+
+	// Data contains all the fields set by the user.
+	Data Fields
+
+	// Time at which the log entry was created.  Set when logged.
+	Time time.Time
+
+	// Level the log entry was logged at.  Set when logged.
+	Level Level
+
+	// Message passed to Trace, Debug, Info, Warn, Error, Fatal or Panic.  Set
+	// when logged.
+	Message string
+}
+
+// NewEntry creates an Entry for the Logger.
+func NewEntry(logger *Logger) *Entry {
+	return &Entry{Logger: logger}
+}
+
+// WithField adds a single field to the Entry.
+func (entry *Entry) WithField(key string, value interface{}) *Entry {
+	return entry.WithFields(Fields{key: value})
+}
+
+// WithFields adds a map of fields to the Entry.
+func (entry *Entry) WithFields(fields Fields) *Entry {
+	data := make(Fields, len(entry.Data)+len(fields))
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	for k, v := range fields {
+		data[k] = v
+	}
+	return &Entry{Logger: entry.Logger, Data: data}
+}
+
+// WithError adds an error as a single field, using the field defined in
+// ErrorKey, to the Entry.
+func (entry *Entry) WithError(err error) *Entry {
+	return entry.WithField(ErrorKey, err)
+}
+
+// Log logs a message at the given level.  Arguments are handled in the manner
+// of fmt.Print.
+func (entry *Entry) Log(level Level, args ...interface{}) {
+	if entry.Logger.IsLevelEnabled(level) {
+		entry.log(level, fmt.Sprint(args...))
+	}
+}
+
+// Logf logs a message at the given level.  Arguments are handled in the
+// manner of fmt.Printf.
+func (entry *Entry) Logf(level Level, format string, args ...interface{}) {
+	if entry.Logger.IsLevelEnabled(level) {
+		entry.log(level, fmt.Sprintf(format, args...))
+	}
+}
+
+// Logln logs a message at the given level.  Arguments are handled in the
+// manner of fmt.Println.
+func (entry *Entry) Logln(level Level, args ...interface{}) {
+	if entry.Logger.IsLevelEnabled(level) {
+		msg := fmt.Sprintln(args...)
+		entry.log(level, msg[:len(msg)-1])
+	}
+}
+
+func (entry *Entry) log(level Level, msg string) {
+	logger := entry.Logger
+	newEntry := &Entry{
+		Logger:  logger,
+		Data:    make(Fields, len(entry.Data)),
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+	}
+	for k, v := range entry.Data {
+		newEntry.Data[k] = v
+	}
+
+	logger.mu.Lock()
+	err := logger.Hooks.Fire(level, newEntry)
+	logger.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to fire hook: %v\n", err)
+	}
+
+	lg := logger.out
+	if len(newEntry.Data) != 0 {
+		lg = lg.WithFields(alog.Fields(newEntry.Data))
+	}
+	switch level.alogLevel() {
+	case alog.PanicLevel:
+		// Do not call lg.Panic, which closes the alog.Logger, since the panic
+		// may be recovered and the Logger used again.
+		lg.Log(alog.PanicLevel, newEntry.Message)
+		lg.Flush()
+		panic(newEntry)
+	case alog.FatalLevel:
+		// The caller exits, whether or not FatalLevel is enabled.
+		lg.Log(alog.FatalLevel, newEntry.Message)
+	case alog.ErrorLevel:
+		lg.Error(newEntry.Message)
+	case alog.WarnLevel:
+		lg.Warn(newEntry.Message)
+	case alog.InfoLevel:
+		lg.Info(newEntry.Message)
+	case alog.DebugLevel:
+		lg.Debug(newEntry.Message)
+	default:
+		lg.Trace(newEntry.Message)
+	}
+}
+
+func (entry *Entry) Trace(args ...interface{})   { entry.Log(TraceLevel, args...) }
+func (entry *Entry) Debug(args ...interface{})   { entry.Log(DebugLevel, args...) }
+func (entry *Entry) Info(args ...interface{})    { entry.Log(InfoLevel, args...) }
+func (entry *Entry) Print(args ...interface{})   { entry.Log(InfoLevel, args...) }
+func (entry *Entry) Warn(args ...interface{})    { entry.Log(WarnLevel, args...) }
+func (entry *Entry) Warning(args ...interface{}) { entry.Log(WarnLevel, args...) }
+func (entry *Entry) Error(args ...interface{})   { entry.Log(ErrorLevel, args...) }
+func (entry *Entry) Fatal(args ...interface{}) {
+	entry.Log(FatalLevel, args...)
+	entry.Logger.Exit(1)
+}
+func (entry *Entry) Panic(args ...interface{}) { entry.Log(PanicLevel, args...) }
+
+func (entry *Entry) Tracef(format string, args ...interface{}) {
+	entry.Logf(TraceLevel, format, args...)
+}
+func (entry *Entry) Debugf(format string, args ...interface{}) {
+	entry.Logf(DebugLevel, format, args...)
+}
+func (entry *Entry) Infof(format string, args ...interface{}) {
+	entry.Logf(InfoLevel, format, args...)
+}
+func (entry *Entry) Printf(format string, args ...interface{}) {
+	entry.Logf(InfoLevel, format, args...)
+}
+func (entry *Entry) Warnf(format string, args ...interface{}) {
+	entry.Logf(WarnLevel, format, args...)
+}
+func (entry *Entry) Warningf(format string, args ...interface{}) {
+	entry.Logf(WarnLevel, format, args...)
+}
+func (entry *Entry) Errorf(format string, args ...interface{}) {
+	entry.Logf(ErrorLevel, format, args...)
+}
+func (entry *Entry) Fatalf(format string, args ...interface{}) {
+	entry.Logf(FatalLevel, format, args...)
+	entry.Logger.Exit(1)
+}
+func (entry *Entry) Panicf(format string, args ...interface{}) {
+	entry.Logf(PanicLevel, format, args...)
+}
+
+func (entry *Entry) Traceln(args ...interface{})   { entry.Logln(TraceLevel, args...) }
+func (entry *Entry) Debugln(args ...interface{})   { entry.Logln(DebugLevel, args...) }
+func (entry *Entry) Infoln(args ...interface{})    { entry.Logln(InfoLevel, args...) }
+func (entry *Entry) Println(args ...interface{})   { entry.Logln(InfoLevel, args...) }
+func (entry *Entry) Warnln(args ...interface{})    { entry.Logln(WarnLevel, args...) }
+func (entry *Entry) Warningln(args ...interface{}) { entry.Logln(WarnLevel, args...) }
+func (entry *Entry) Errorln(args ...interface{})   { entry.Logln(ErrorLevel, args...) }
+func (entry *Entry) Fatalln(args ...interface{}) {
+	entry.Logln(FatalLevel, args...)
+	entry.Logger.Exit(1)
+}
+func (entry *Entry) Panicln(args ...interface{}) { entry.Logln(PanicLevel, args...) }
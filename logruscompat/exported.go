@@ -0,0 +1,111 @@
+package logruscompat
+
+import (
+	"os"
+	"sync"
+
+	"github.com/gammazero/alog"
+)
+
+var (
+	stdMutex sync.Mutex
+	std      *Logger
+)
+
+// StandardLogger returns the Logger used by the package-level functions.
+// Unless one is set by SetStandardLogger, it is created on first use and
+// writes text to os.Stderr, which is the logrus default.
+func StandardLogger() *Logger {
+	stdMutex.Lock()
+	defer stdMutex.Unlock()
+	if std == nil {
+		std = New(alog.NewText(os.Stderr, alog.TraceLevel, "", ""))
+	}
+	return std
+}
+
+// SetStandardLogger sets the Logger used by the package-level functions.
+// This should be called before any of them are used.
+func SetStandardLogger(logger *Logger) {
+	stdMutex.Lock()
+	std = logger
+	stdMutex.Unlock()
+}
+
+// SetLevel sets the standard logger level.
+func SetLevel(level Level) { StandardLogger().SetLevel(level) }
+
+// GetLevel returns the standard logger level.
+func GetLevel() Level { return StandardLogger().GetLevel() }
+
+// IsLevelEnabled checks if the log level of the standard logger is greater
+// than the level param.
+func IsLevelEnabled(level Level) bool {
+	return StandardLogger().IsLevelEnabled(level)
+}
+
+// AddHook adds a hook to the standard logger hooks.
+func AddHook(hook Hook) { StandardLogger().AddHook(hook) }
+
+// WithField creates an entry from the standard logger and adds a field to it.
+func WithField(key string, value interface{}) *Entry {
+	return StandardLogger().WithField(key, value)
+}
+
+// WithFields creates an entry from the standard logger and adds multiple
+// fields to it.
+func WithFields(fields Fields) *Entry {
+	return StandardLogger().WithFields(fields)
+}
+
+// WithError creates an entry from the standard logger and adds an error to
+// it, using the field defined in ErrorKey.
+func WithError(err error) *Entry { return StandardLogger().WithError(err) }
+
+func Trace(args ...interface{})   { StandardLogger().Trace(args...) }
+func Debug(args ...interface{})   { StandardLogger().Debug(args...) }
+func Info(args ...interface{})    { StandardLogger().Info(args...) }
+func Print(args ...interface{})   { StandardLogger().Print(args...) }
+func Warn(args ...interface{})    { StandardLogger().Warn(args...) }
+func Warning(args ...interface{}) { StandardLogger().Warning(args...) }
+func Error(args ...interface{})   { StandardLogger().Error(args...) }
+func Fatal(args ...interface{})   { StandardLogger().Fatal(args...) }
+func Panic(args ...interface{})   { StandardLogger().Panic(args...) }
+
+func Tracef(format string, args ...interface{}) {
+	StandardLogger().Tracef(format, args...)
+}
+func Debugf(format string, args ...interface{}) {
+	StandardLogger().Debugf(format, args...)
+}
+func Infof(format string, args ...interface{}) {
+	StandardLogger().Infof(format, args...)
+}
+func Printf(format string, args ...interface{}) {
+	StandardLogger().Printf(format, args...)
+}
+func Warnf(format string, args ...interface{}) {
+	StandardLogger().Warnf(format, args...)
+}
+func Warningf(format string, args ...interface{}) {
+	StandardLogger().Warningf(format, args...)
+}
+func Errorf(format string, args ...interface{}) {
+	StandardLogger().Errorf(format, args...)
+}
+func Fatalf(format string, args ...interface{}) {
+	StandardLogger().Fatalf(format, args...)
+}
+func Panicf(format string, args ...interface{}) {
+	StandardLogger().Panicf(format, args...)
+}
+
+func Traceln(args ...interface{})   { StandardLogger().Traceln(args...) }
+func Debugln(args ...interface{})   { StandardLogger().Debugln(args...) }
+func Infoln(args ...interface{})    { StandardLogger().Infoln(args...) }
+func Println(args ...interface{})   { StandardLogger().Println(args...) }
+func Warnln(args ...interface{})    { StandardLogger().Warnln(args...) }
+func Warningln(args ...interface{}) { StandardLogger().Warningln(args...) }
+func Errorln(args ...interface{})   { StandardLogger().Errorln(args...) }
+func Fatalln(args ...interface{})   { StandardLogger().Fatalln(args...) }
+func Panicln(args ...interface{})   { StandardLogger().Panicln(args...) }
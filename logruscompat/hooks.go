@@ -0,0 +1,29 @@
+package logruscompat
+
+// Hook is called when an entry is logged at one of the levels returned by
+// Levels.  Hooks are fired synchronously, by the goroutine that logs the
+// entry, before the entry is handed to the alog.Logger.
+type Hook interface {
+	Levels() []Level
+	Fire(*Entry) error
+}
+
+// LevelHooks maps each level to the hooks fired at that level.
+type LevelHooks map[Level][]Hook
+
+// Add a hook to an instance of LevelHooks.
+func (hooks LevelHooks) Add(hook Hook) {
+	for _, level := range hook.Levels() {
+		hooks[level] = append(hooks[level], hook)
+	}
+}
+
+// Fire all the hooks for the given level.
+func (hooks LevelHooks) Fire(level Level, entry *Entry) error {
+	for _, hook := range hooks[level] {
+		if err := hook.Fire(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package logruscompat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gammazero/alog"
+)
+
+// Level is a logrus logging level.
+type Level uint32
+
+// These are the logrus logging levels, in order of decreasing severity.
+const (
+	PanicLevel Level = iota
+	FatalLevel
+	ErrorLevel
+	WarnLevel
+	InfoLevel
+	DebugLevel
+	TraceLevel
+)
+
+// AllLevels is a slice of all logging levels, for use by hooks.
+var AllLevels = []Level{
+	PanicLevel,
+	FatalLevel,
+	ErrorLevel,
+	WarnLevel,
+	InfoLevel,
+	DebugLevel,
+	TraceLevel,
+}
+
+var levelNames = [TraceLevel + 1]string{
+	"panic", "fatal", "error", "warning", "info", "debug", "trace"}
+
+// String converts a Level to the name used by logrus.
+func (level Level) String() string {
+	if level > TraceLevel {
+		return "unknown"
+	}
+	return levelNames[level]
+}
+
+// ParseLevel takes a string level and returns the Level constant.
+func ParseLevel(lvl string) (Level, error) {
+	switch strings.ToLower(lvl) {
+	case "panic":
+		return PanicLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "debug":
+		return DebugLevel, nil
+	case "trace":
+		return TraceLevel, nil
+	}
+	return 0, fmt.Errorf("not a valid logrus Level: %q", lvl)
+}
+
+// alogLevel converts a logrus level to an alog level.
+func (level Level) alogLevel() alog.Level {
+	switch level {
+	case PanicLevel:
+		return alog.PanicLevel
+	case FatalLevel:
+		return alog.FatalLevel
+	case ErrorLevel:
+		return alog.ErrorLevel
+	case WarnLevel:
+		return alog.WarnLevel
+	case InfoLevel:
+		return alog.InfoLevel
+	case DebugLevel:
+		return alog.DebugLevel
+	}
+	return alog.TraceLevel
+}
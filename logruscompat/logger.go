@@ -0,0 +1,190 @@
+/*
+Package logruscompat provides the commonly used parts of the logrus API backed
+by an alog.Logger.
+
+Code written against github.com/sirupsen/logrus can be migrated to alog by
+replacing the logrus import with this package, and creating the Logger with
+New:
+
+	import log "github.com/gammazero/alog/logruscompat"
+
+	logger := log.New(alog.NewText(os.Stderr, alog.TraceLevel, "", ""))
+	logger.SetLevel(log.InfoLevel)
+	logger.WithField("animal", "walrus").Info("A walrus appears")
+
+Level filtering is done by this package, according to SetLevel, before entries
+are passed to the alog.Logger.  The alog.Logger should therefore be created at
+alog.TraceLevel, or with alog.NoLevel, so that it does not discard entries this
+package lets through.
+
+Panic and Fatal behave as in logrus.  Panic logs the entry, flushes the
+alog.Logger, and calls panic() with the *Entry, so that a recovered panic
+leaves the alog.Logger usable.  Fatal logs the entry, if FatalLevel is
+enabled, and then calls Exit(1), which flushes the alog.Logger and calls the
+Logger's ExitFunc.
+*/
+package logruscompat
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gammazero/alog"
+)
+
+// Fields is a map of fields to attach to an entry.
+type Fields map[string]interface{}
+
+// ErrorKey is the field used by WithError.
+const ErrorKey = alog.ErrorField
+
+// Logger provides the logrus.Logger API, writing entries to an alog.Logger.
+type Logger struct {
+	// Hooks for the logger instance.  Use AddHook to add hooks once the
+	// Logger is in use.
+	Hooks LevelHooks
+
+	// ExitFunc is the function called by Exit, after flushing the
+	// alog.Logger.  If nil, os.Exit is called.
+	ExitFunc func(code int)
+
+	out   alog.Logger
+	level uint32
+	mu    sync.Mutex
+}
+
+// New creates a Logger that writes to the given alog.Logger.  The Logger
+// initially logs at InfoLevel, the same as logrus.
+func New(lg alog.Logger) *Logger {
+	return &Logger{
+		Hooks: make(LevelHooks),
+		out:   lg,
+		level: uint32(InfoLevel),
+	}
+}
+
+// Alog returns the alog.Logger that the Logger writes to.
+func (logger *Logger) Alog() alog.Logger { return logger.out }
+
+// SetLevel sets the logger level.
+func (logger *Logger) SetLevel(level Level) {
+	atomic.StoreUint32(&logger.level, uint32(level))
+}
+
+// GetLevel returns the logger level.
+func (logger *Logger) GetLevel() Level {
+	return Level(atomic.LoadUint32(&logger.level))
+}
+
+// IsLevelEnabled checks if the log level of the logger is greater than the
+// level param.
+func (logger *Logger) IsLevelEnabled(level Level) bool {
+	return logger.GetLevel() >= level
+}
+
+// Exit flushes the alog.Logger, and then calls the ExitFunc with code.
+func (logger *Logger) Exit(code int) {
+	logger.out.Flush()
+	if logger.ExitFunc == nil {
+		os.Exit(code)
+	}
+	logger.ExitFunc(code)
+}
+
+// AddHook adds a hook to the logger hooks.
+func (logger *Logger) AddHook(hook Hook) {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	logger.Hooks.Add(hook)
+}
+
+// ReplaceHooks replaces the logger hooks and returns the old ones.
+func (logger *Logger) ReplaceHooks(hooks LevelHooks) LevelHooks {
+	logger.mu.Lock()
+	oldHooks := logger.Hooks
+	logger.Hooks = hooks
+	logger.mu.Unlock()
+	return oldHooks
+}
+
+// WithField allocates a new entry and adds a field to it.
+func (logger *Logger) WithField(key string, value interface{}) *Entry {
+	return NewEntry(logger).WithField(key, value)
+}
+
+// WithFields allocates a new entry and adds multiple fields to it.
+func (logger *Logger) WithFields(fields Fields) *Entry {
+	return NewEntry(logger).WithFields(fields)
+}
+
+// WithError allocates a new entry and adds an error to it, using the field
+// defined in ErrorKey.
+func (logger *Logger) WithError(err error) *Entry {
+	return NewEntry(logger).WithError(err)
+}
+
+func (logger *Logger) Log(level Level, args ...interface{}) {
+	NewEntry(logger).Log(level, args...)
+}
+func (logger *Logger) Logf(level Level, format string, args ...interface{}) {
+	NewEntry(logger).Logf(level, format, args...)
+}
+func (logger *Logger) Logln(level Level, args ...interface{}) {
+	NewEntry(logger).Logln(level, args...)
+}
+
+func (logger *Logger) Trace(args ...interface{})   { logger.Log(TraceLevel, args...) }
+func (logger *Logger) Debug(args ...interface{})   { logger.Log(DebugLevel, args...) }
+func (logger *Logger) Info(args ...interface{})    { logger.Log(InfoLevel, args...) }
+func (logger *Logger) Print(args ...interface{})   { logger.Log(InfoLevel, args...) }
+func (logger *Logger) Warn(args ...interface{})    { logger.Log(WarnLevel, args...) }
+func (logger *Logger) Warning(args ...interface{}) { logger.Log(WarnLevel, args...) }
+func (logger *Logger) Error(args ...interface{})   { logger.Log(ErrorLevel, args...) }
+func (logger *Logger) Fatal(args ...interface{}) {
+	logger.Log(FatalLevel, args...)
+	logger.Exit(1)
+}
+func (logger *Logger) Panic(args ...interface{}) { logger.Log(PanicLevel, args...) }
+
+func (logger *Logger) Tracef(format string, args ...interface{}) {
+	logger.Logf(TraceLevel, format, args...)
+}
+func (logger *Logger) Debugf(format string, args ...interface{}) {
+	logger.Logf(DebugLevel, format, args...)
+}
+func (logger *Logger) Infof(format string, args ...interface{}) {
+	logger.Logf(InfoLevel, format, args...)
+}
+func (logger *Logger) Printf(format string, args ...interface{}) {
+	logger.Logf(InfoLevel, format, args...)
+}
+func (logger *Logger) Warnf(format string, args ...interface{}) {
+	logger.Logf(WarnLevel, format, args...)
+}
+func (logger *Logger) Warningf(format string, args ...interface{}) {
+	logger.Logf(WarnLevel, format, args...)
+}
+func (logger *Logger) Errorf(format string, args ...interface{}) {
+	logger.Logf(ErrorLevel, format, args...)
+}
+func (logger *Logger) Fatalf(format string, args ...interface{}) {
+	logger.Logf(FatalLevel, format, args...)
+	logger.Exit(1)
+}
+func (logger *Logger) Panicf(format string, args ...interface{}) {
+	logger.Logf(PanicLevel, format, args...)
+}
+
+func (logger *Logger) Traceln(args ...interface{})   { logger.Logln(TraceLevel, args...) }
+func (logger *Logger) Debugln(args ...interface{})   { logger.Logln(DebugLevel, args...) }
+func (logger *Logger) Infoln(args ...interface{})    { logger.Logln(InfoLevel, args...) }
+func (logger *Logger) Println(args ...interface{})   { logger.Logln(InfoLevel, args...) }
+func (logger *Logger) Warnln(args ...interface{})    { logger.Logln(WarnLevel, args...) }
+func (logger *Logger) Warningln(args ...interface{}) { logger.Logln(WarnLevel, args...) }
+func (logger *Logger) Errorln(args ...interface{})   { logger.Logln(ErrorLevel, args...) }
+func (logger *Logger) Fatalln(args ...interface{}) {
+	logger.Logln(FatalLevel, args...)
+	logger.Exit(1)
+}
+func (logger *Logger) Panicln(args ...interface{}) { logger.Logln(PanicLevel, args...) }
@@ -0,0 +1,116 @@
+package logruscompat
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gammazero/alog"
+)
+
+type testHook struct {
+	fired []*Entry
+}
+
+func (h *testHook) Levels() []Level { return []Level{ErrorLevel, WarnLevel} }
+
+func (h *testHook) Fire(entry *Entry) error {
+	entry.Data["hooked"] = true
+	h.fired = append(h.fired, entry)
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := alog.NewText(buf, alog.TraceLevel, " ", "")
+	logger := New(lg)
+	hook := new(testHook)
+	logger.AddHook(hook)
+
+	logger.Debug("hidden")
+	logger.SetLevel(TraceLevel)
+	logger.Traceln("trace", "me")
+	logger.WithField("animal", "walrus").Infof("%d tusks", 2)
+	logger.WithError(errors.New("boom")).Warning("careful")
+	logger.Logf(ErrorLevel, "code %d", 7)
+	lg.Close()
+
+	if len(hook.fired) != 2 {
+		t.Fatal("expected 2 hooks fired, got", len(hook.fired))
+	}
+	if hook.fired[0].Message != "careful" || hook.fired[0].Level != WarnLevel {
+		t.Error("bad hooked entry:", hook.fired[0])
+	}
+
+	expect := []string{
+		" TRACE trace me",
+		" INFO 2 tusks (animal=walrus)",
+		" WARN careful",
+		" ERROR code 7 (hooked=true)",
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(expect) {
+		t.Fatalf("expected %d lines, got %d: %q", len(expect), len(lines), lines)
+	}
+	for i := range expect {
+		if !strings.HasPrefix(lines[i], expect[i]) {
+			t.Errorf("expected line %q, got %q", expect[i], lines[i])
+		}
+	}
+	if !strings.Contains(lines[2], "(error=boom)") || !strings.Contains(lines[2], "(hooked=true)") {
+		t.Error("missing fields:", lines[2])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, lvl := range AllLevels {
+		parsed, err := ParseLevel(lvl.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != lvl {
+			t.Errorf("expected %s, got %s", lvl, parsed)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected error parsing invalid level")
+	}
+}
+
+func TestPanicFatal(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := alog.NewText(buf, alog.TraceLevel, " ", "")
+	defer lg.Close()
+	logger := New(lg)
+	var codes []int
+	logger.ExitFunc = func(code int) { codes = append(codes, code) }
+
+	func() {
+		defer func() {
+			if e, ok := recover().(*Entry); !ok || e.Message != "oh no" {
+				t.Error("expected panic with entry, got", e)
+			}
+		}()
+		logger.WithField("k", 1).Panic("oh no")
+	}()
+	// The alog.Logger is still usable after a recovered panic.
+	logger.Info("still here")
+	logger.Fatal("fatal")
+	logger.SetLevel(PanicLevel)
+	logger.Fatalf("not logged %d", 1)
+	lg.Flush()
+
+	if len(codes) != 2 || codes[0] != 1 || codes[1] != 1 {
+		t.Error("expected 2 exits with code 1, got", codes)
+	}
+	out := buf.String()
+	for _, want := range []string{" PANIC oh no", " INFO still here", " FATAL fatal"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+	if strings.Contains(out, "not logged") {
+		t.Error("disabled Fatal was logged")
+	}
+}
@@ -0,0 +1,117 @@
+package alog
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// NameField is the field that holds the name of a Logger created by Named.
+const NameField = "logger"
+
+// ParseNameLevels parses a list of name prefixes and levels, such as
+// "db=debug,http=warn", into a map for SetNameLevels.
+func ParseNameLevels(spec string) (map[string]Level, error) {
+	levels := make(map[string]Level)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		eq := strings.IndexByte(item, '=')
+		if eq == -1 {
+			return nil, fmt.Errorf("missing level for name %q", item)
+		}
+		name := strings.TrimSpace(item[:eq])
+		if name == "" {
+			return nil, fmt.Errorf("missing name in %q", item)
+		}
+		level, err := ParseLevel(strings.TrimSpace(item[eq+1:]))
+		if err != nil {
+			return nil, err
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
+
+// nameLevels holds the levels set for name prefixes.  It is replaced, not
+// modified, when the levels are changed.
+type nameLevels struct {
+	v atomic.Value
+}
+
+func (nl *nameLevels) store(levels map[string]Level) {
+	copied := make(map[string]Level, len(levels))
+	for name, level := range levels {
+		copied[name] = clampLevel(level)
+	}
+	nl.v.Store(copied)
+}
+
+// lookup returns the level set for the longest prefix of name, made of whole
+// dot-separated components, that has a level.
+func (nl *nameLevels) lookup(name string) (Level, bool) {
+	levels, _ := nl.v.Load().(map[string]Level)
+	if len(levels) == 0 {
+		return NoLevel, false
+	}
+	for name != "" {
+		if level, ok := levels[name]; ok {
+			return level, true
+		}
+		dot := strings.LastIndexByte(name, '.')
+		if dot == -1 {
+			break
+		}
+		name = name[:dot]
+	}
+	return NoLevel, false
+}
+
+func (a *logger) Named(name string) Logger {
+	return &fieldLogger{
+		logger: a,
+		fields: Fields{NameField: name},
+		name:   name,
+	}
+}
+
+func (a *logger) SetNameLevels(levels map[string]Level) {
+	a.nameLevels.store(levels)
+}
+
+// logableNamed reports whether entries at the given level are logged by the
+// Logger with the given name.
+func (a *logger) logableNamed(name string, level Level) bool {
+	level = legacyLevel(level)
+	if a.level == NoLevel {
+		return true
+	}
+	if name != "" {
+		if nameLevel, ok := a.nameLevels.lookup(name); ok {
+			return nameLevel != NoLevel && level <= nameLevel
+		}
+	}
+	return level <= a.level
+}
+
+func (f *fieldLogger) Named(name string) Logger {
+	if f.off {
+		return f
+	}
+	if f.name != "" {
+		name = f.name + "." + name
+	}
+	fields := make(Fields, len(f.fields)+1)
+	for k, v := range f.fields {
+		fields[k] = v
+	}
+	fields[NameField] = name
+	return &fieldLogger{
+		logger: f.logger,
+		fields: fields,
+		gate:   f.gate,
+		name:   name,
+	}
+}
@@ -0,0 +1,86 @@
+package alog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseNameLevels(t *testing.T) {
+	levels, err := ParseNameLevels("db=debug, http=warn,,db.pool=none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levels) != 3 || levels["db"] != DebugLevel || levels["http"] != WarnLevel ||
+		levels["db.pool"] != NoLevel {
+		t.Error("wrong levels:", levels)
+	}
+	for _, spec := range []string{"db", "=debug", "db=loud"} {
+		if _, err = ParseNameLevels(spec); err == nil {
+			t.Errorf("expected error parsing %q", spec)
+		}
+	}
+}
+
+func TestNamed(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+	db := lg.Named("db")
+	pool := db.WithField("size", 4).Named("pool")
+	dbx := lg.Named("dbx")
+	http := lg.Named("http")
+
+	levels, err := ParseNameLevels("db=debug,http=warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg.SetNameLevels(levels)
+
+	if !pool.Enabled(DebugLevel) || dbx.Enabled(DebugLevel) || http.Enabled(InfoLevel) {
+		t.Error("wrong levels enabled for names")
+	}
+	lg.Debug("root debug")
+	db.Debug("db debug")
+	pool.Debug("pool debug")
+	dbx.Debug("dbx debug")
+	http.Info("http info")
+	http.Warn("http warn")
+
+	lg.SetNameLevels(map[string]Level{"db.pool": NoLevel})
+	pool.Error("pool error")
+	db.Debug("db debug again")
+	db.Info("db info")
+	lg.Close()
+
+	expect := []string{
+		" DEBUG db debug (logger=db)",
+		" DEBUG pool debug",
+		" WARN http warn (logger=http)",
+		" INFO db info (logger=db)",
+	}
+	lines := checkLines(t, buf, expect...)
+	if !strings.Contains(lines[1], "(logger=db.pool)") || !strings.Contains(lines[1], "(size=4)") {
+		t.Error("missing fields of named logger:", lines[1])
+	}
+}
+
+func TestNamedWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+	lg.SetNameLevels(map[string]Level{"http": ErrorLevel, "db": DebugLevel})
+
+	fmt.Fprintln(lg.Named("http").Writer(InfoLevel), "http info")
+	fmt.Fprintln(lg.Named("http").Writer(ErrorLevel), "http error")
+	fmt.Fprintln(lg.Named("db").Writer(DebugLevel), "db debug")
+	fmt.Fprintln(lg.Once("w").Writer(InfoLevel), "once")
+	fmt.Fprintln(lg.Once("w").Writer(InfoLevel), "twice")
+	lg.Close()
+
+	expect := []string{
+		" ERROR http error (logger=http)",
+		" DEBUG db debug (logger=db)",
+		" INFO once",
+	}
+	checkLines(t, buf, expect...)
+}
@@ -0,0 +1,97 @@
+/*
+Package otelalog correlates alog entries with OpenTelemetry traces.
+
+When an entry is logged by a context-aware method, such as InfoContext, with a
+context that has a valid span, the entry is given the TraceIDField,
+SpanIDField, and TraceFlagsField fields.  Optionally, the entry is also
+recorded as an event on the span, if the span is recording.
+
+	otelalog.Register(true)
+
+	ctx, span := tracer.Start(ctx, "handle")
+	defer span.End()
+	logger.InfoContext(ctx, "handling request")
+
+This package uses only the OpenTelemetry API, so that the core alog package
+has no dependency on OpenTelemetry.
+*/
+package otelalog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gammazero/alog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fields added to entries logged with a context that has a span.
+const (
+	TraceIDField    = "trace_id"
+	SpanIDField     = "span_id"
+	TraceFlagsField = "trace_flags"
+)
+
+// EventName is the name of the span events that entries are recorded as.
+const EventName = "log"
+
+// Attributes of the span events that entries are recorded as.  The fields of
+// the entry, other than the trace correlation fields, are also recorded as
+// attributes, with their values formatted in the manner of fmt.Sprint.
+const (
+	SeverityAttribute = "log.severity"
+	MessageAttribute  = "log.message"
+)
+
+// Register registers TraceFields with alog.RegisterContextExtractor, and, if
+// spanEvents is true, registers SpanEvent with alog.RegisterContextHook.
+// Call Register once, before logging.  Register returns a function that
+// unregisters them.
+func Register(spanEvents bool) func() {
+	unregisterFields := alog.RegisterContextExtractor(TraceFields)
+	unregisterEvents := func() {}
+	if spanEvents {
+		unregisterEvents = alog.RegisterContextHook(SpanEvent)
+	}
+	return func() {
+		unregisterFields()
+		unregisterEvents()
+	}
+}
+
+// TraceFields is an alog.ContextExtractor that returns the trace ID, span ID,
+// and trace flags of the span in ctx, if ctx has a valid span.
+func TraceFields(ctx context.Context) alog.Fields {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return alog.Fields{
+		TraceIDField:    sc.TraceID().String(),
+		SpanIDField:     sc.SpanID().String(),
+		TraceFlagsField: sc.TraceFlags().String(),
+	}
+}
+
+// SpanEvent is an alog.ContextHook that records the entry as an event on the
+// span in ctx, if the span is recording.
+func SpanEvent(ctx context.Context, e *alog.Entry) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	attrs := make([]attribute.KeyValue, 0, len(e.Fields)+2)
+	if e.Level != alog.NoLevel {
+		attrs = append(attrs, attribute.String(SeverityAttribute, e.Level.String()))
+	}
+	attrs = append(attrs, attribute.String(MessageAttribute, e.Message))
+	for k, v := range e.Fields {
+		switch k {
+		case TraceIDField, SpanIDField, TraceFlagsField:
+			continue
+		}
+		attrs = append(attrs, attribute.String(k, fmt.Sprint(v)))
+	}
+	span.AddEvent(EventName, trace.WithTimestamp(e.Time), trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,67 @@
+package otelalog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gammazero/alog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraceCorrelation(t *testing.T) {
+	t.Cleanup(Register(true))
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "op")
+
+	buf := new(bytes.Buffer)
+	lg := alog.NewJSON(buf, alog.InfoLevel, "")
+	lazy := alog.LazyFunc(func() interface{} { return 42 })
+	lg.WithFields(alog.Fields{"user": "rick", "answer": lazy}).InfoContext(ctx, "in span")
+	lg.DebugContext(ctx, "hidden")
+	lg.InfoContext(context.Background(), "no span")
+	span.End()
+	lg.Close()
+
+	sc := span.SpanContext()
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), lines)
+	}
+	for _, want := range []string{
+		`"trace_id":"` + sc.TraceID().String() + `"`,
+		`"span_id":"` + sc.SpanID().String() + `"`,
+		`"trace_flags":"01"`,
+	} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("missing %s in %s", want, lines[0])
+		}
+	}
+	if strings.Contains(lines[1], "trace_id") {
+		t.Error("unexpected trace fields:", lines[1])
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatal("expected 1 span, got", len(spans))
+	}
+	events := spans[0].Events()
+	if len(events) != 1 {
+		t.Fatal("expected 1 span event, got", len(events))
+	}
+	if events[0].Name != EventName {
+		t.Error("wrong event name:", events[0].Name)
+	}
+	attrs := map[string]string{}
+	for _, kv := range events[0].Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs[MessageAttribute] != "in span" || attrs[SeverityAttribute] != "info" ||
+		attrs["user"] != "rick" || attrs["answer"] != "42" || attrs[TraceIDField] != "" {
+		t.Error("wrong event attributes:", attrs)
+	}
+}
@@ -0,0 +1,406 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gammazero/alog"
+)
+
+// Fields that are mapped to the trace context of a LogRecord, instead of to
+// its attributes.  These are the fields added by the otelalog package.
+const (
+	traceIDField    = "trace_id"
+	spanIDField     = "span_id"
+	traceFlagsField = "trace_flags"
+)
+
+// value is an OTLP AnyValue.  Exactly one of its fields is used, according to
+// kind.
+type value struct {
+	kind valueKind
+	str  string
+	num  int64
+	flt  float64
+}
+
+type valueKind int
+
+const (
+	stringValue valueKind = iota
+	boolValue
+	intValue
+	doubleValue
+)
+
+type keyValue struct {
+	key   string
+	value value
+}
+
+// record is an OTLP LogRecord.
+type record struct {
+	time           uint64
+	observedTime   uint64
+	severityNumber int
+	severityText   string
+	body           string
+	attributes     []keyValue
+	traceID        []byte
+	spanID         []byte
+	flags          uint32
+}
+
+// severities maps the standard alog levels, from most to least severe, to
+// OTLP severity numbers.
+var severities = []struct {
+	level  alog.Level
+	number int
+}{
+	{alog.PanicLevel, 24},
+	{alog.FatalLevel, 21},
+	{alog.ErrorLevel, 17},
+	{alog.WarnLevel, 13},
+	{alog.InfoLevel, 9},
+	{alog.DebugLevel, 5},
+	{alog.TraceLevel, 1},
+}
+
+// severityNumber maps a level to an OTLP severity number.  A custom level
+// between two standard levels is mapped to one more than the number of the
+// less severe level.  Entries without a level have an unspecified severity.
+func severityNumber(level alog.Level) int {
+	if level == alog.NoLevel {
+		return 0
+	}
+	for i, s := range severities {
+		if level == s.level {
+			return s.number
+		}
+		if level < s.level {
+			if i == 0 {
+				return s.number
+			}
+			return s.number + 1
+		}
+	}
+	return 1
+}
+
+// uintValue returns an int value if n fits in an int64, and otherwise a string
+// value.
+func uintValue(n uint64) value {
+	if n > math.MaxInt64 {
+		return value{kind: stringValue, str: strconv.FormatUint(n, 10)}
+	}
+	return value{kind: intValue, num: int64(n)}
+}
+
+func toValue(v interface{}) value {
+	switch v := v.(type) {
+	case string:
+		return value{kind: stringValue, str: v}
+	case bool:
+		var n int64
+		if v {
+			n = 1
+		}
+		return value{kind: boolValue, num: n}
+	case int:
+		return value{kind: intValue, num: int64(v)}
+	case int8:
+		return value{kind: intValue, num: int64(v)}
+	case int16:
+		return value{kind: intValue, num: int64(v)}
+	case int32:
+		return value{kind: intValue, num: int64(v)}
+	case int64:
+		return value{kind: intValue, num: v}
+	case uint8:
+		return value{kind: intValue, num: int64(v)}
+	case uint16:
+		return value{kind: intValue, num: int64(v)}
+	case uint32:
+		return value{kind: intValue, num: int64(v)}
+	case uint:
+		return uintValue(uint64(v))
+	case uint64:
+		return uintValue(v)
+	case uintptr:
+		return uintValue(uint64(v))
+	case float32:
+		return value{kind: doubleValue, flt: float64(v)}
+	case float64:
+		return value{kind: doubleValue, flt: v}
+	case error:
+		return value{kind: stringValue, str: v.Error()}
+	}
+	return value{kind: stringValue, str: fmt.Sprint(v)}
+}
+
+func toAttributes(fields alog.Fields) []keyValue {
+	if len(fields) == 0 {
+		return nil
+	}
+	attrs := make([]keyValue, 0, len(fields))
+	for k, v := range fields {
+		attrs = append(attrs, keyValue{key: k, value: toValue(v)})
+	}
+	return attrs
+}
+
+// newRecord converts an entry to a LogRecord.  Trace correlation fields with
+// valid values are mapped to the record's trace context.
+func newRecord(e *alog.Entry, observed time.Time) record {
+	r := record{
+		time:           uint64(e.Time.UnixNano()),
+		observedTime:   uint64(observed.UnixNano()),
+		severityNumber: severityNumber(e.Level),
+		body:           e.Message,
+	}
+	if e.Level != alog.NoLevel {
+		r.severityText = e.Level.String()
+	}
+	attrs := make([]keyValue, 0, len(e.Fields))
+	for k, v := range e.Fields {
+		switch k {
+		case traceIDField:
+			if id := decodeID(v, 16); id != nil {
+				r.traceID = id
+				continue
+			}
+		case spanIDField:
+			if id := decodeID(v, 8); id != nil {
+				r.spanID = id
+				continue
+			}
+		case traceFlagsField:
+			if s, ok := v.(string); ok {
+				if flags, err := strconv.ParseUint(s, 16, 8); err == nil {
+					r.flags = uint32(flags)
+					continue
+				}
+			}
+		}
+		attrs = append(attrs, keyValue{key: k, value: toValue(v)})
+	}
+	r.attributes = attrs
+	return r
+}
+
+// decodeID decodes a hex string of an ID of the given size.  It returns nil
+// if v is not such a string, or if the ID is all zeros.
+func decodeID(v interface{}, size int) []byte {
+	s, ok := v.(string)
+	if !ok || len(s) != size*2 {
+		return nil
+	}
+	id, err := hex.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	for _, b := range id {
+		if b != 0 {
+			return id
+		}
+	}
+	return nil
+}
+
+// ---- OTLP/JSON encoding ----
+
+func appendJSONString(buf []byte, s string) []byte {
+	b, _ := json.Marshal(s)
+	return append(buf, b...)
+}
+
+func appendJSONValue(buf []byte, v value) []byte {
+	switch v.kind {
+	case boolValue:
+		buf = strconv.AppendBool(append(buf, `{"boolValue":`...), v.num != 0)
+		return append(buf, '}')
+	case intValue:
+		buf = strconv.AppendInt(append(buf, `{"intValue":"`...), v.num, 10)
+		return append(buf, `"}`...)
+	case doubleValue:
+		if math.IsNaN(v.flt) || math.IsInf(v.flt, 0) {
+			return appendJSONString(append(buf, `{"doubleValue":`...), strconv.FormatFloat(v.flt, 'g', -1, 64))
+		}
+		buf = strconv.AppendFloat(append(buf, `{"doubleValue":`...), v.flt, 'g', -1, 64)
+		return append(buf, '}')
+	}
+	buf = appendJSONString(append(buf, `{"stringValue":`...), v.str)
+	return append(buf, '}')
+}
+
+func appendJSONAttributes(buf []byte, attrs []keyValue) []byte {
+	buf = append(buf, `"attributes":[`...)
+	for i, kv := range attrs {
+		if i != 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendJSONString(append(buf, `{"key":`...), kv.key)
+		buf = appendJSONValue(append(buf, `,"value":`...), kv.value)
+		buf = append(buf, '}')
+	}
+	return append(buf, ']')
+}
+
+// encodeJSON encodes an ExportLogsServiceRequest in the OTLP/JSON format.
+func (x *Exporter) encodeJSON(buf []byte, records []record) []byte {
+	buf = append(buf, `{"resourceLogs":[{"resource":{`...)
+	buf = appendJSONAttributes(buf, x.resource)
+	buf = append(buf, `},"scopeLogs":[{"scope":{"name":`...)
+	buf = appendJSONString(buf, x.scopeName)
+	buf = append(buf, `},"logRecords":[`...)
+	for i := range records {
+		r := &records[i]
+		if i != 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendUint(append(buf, `{"timeUnixNano":"`...), r.time, 10)
+		buf = strconv.AppendUint(append(buf, `","observedTimeUnixNano":"`...), r.observedTime, 10)
+		buf = append(buf, '"')
+		if r.severityNumber != 0 {
+			buf = strconv.AppendInt(append(buf, `,"severityNumber":`...), int64(r.severityNumber), 10)
+		}
+		if r.severityText != "" {
+			buf = appendJSONString(append(buf, `,"severityText":`...), r.severityText)
+		}
+		buf = appendJSONValue(append(buf, `,"body":`...), value{str: r.body})
+		buf = appendJSONAttributes(append(buf, ','), r.attributes)
+		if r.traceID != nil {
+			buf = append(buf, `,"traceId":"`...)
+			buf = append(buf, hex.EncodeToString(r.traceID)...)
+			buf = append(buf, '"')
+		}
+		if r.spanID != nil {
+			buf = append(buf, `,"spanId":"`...)
+			buf = append(buf, hex.EncodeToString(r.spanID)...)
+			buf = append(buf, '"')
+		}
+		if r.flags != 0 {
+			buf = strconv.AppendUint(append(buf, `,"flags":`...), uint64(r.flags), 10)
+		}
+		buf = append(buf, '}')
+	}
+	return append(buf, `]}]}]}`...)
+}
+
+// ---- OTLP/protobuf encoding ----
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func appendVarint(buf []byte, v uint64) []byte {
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendTag(buf []byte, field, wireType int) []byte {
+	return appendVarint(buf, uint64(field)<<3|uint64(wireType))
+}
+
+func appendBytesField(buf []byte, field int, b []byte) []byte {
+	buf = appendTag(buf, field, wireBytes)
+	buf = appendVarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+func appendStringField(buf []byte, field int, s string) []byte {
+	buf = appendTag(buf, field, wireBytes)
+	buf = appendVarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// appendMessage appends an embedded message field, encoded by fn.
+func appendMessage(buf []byte, field int, fn func([]byte) []byte) []byte {
+	msg := fn(nil)
+	return appendBytesField(buf, field, msg)
+}
+
+// appendProtoValue appends the fields of an AnyValue.
+func appendProtoValue(buf []byte, v value) []byte {
+	switch v.kind {
+	case boolValue:
+		buf = appendTag(buf, 2, wireVarint)
+		return appendVarint(buf, uint64(v.num))
+	case intValue:
+		buf = appendTag(buf, 3, wireVarint)
+		return appendVarint(buf, uint64(v.num))
+	case doubleValue:
+		buf = appendTag(buf, 4, wireFixed64)
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.flt))
+	}
+	return appendStringField(buf, 1, v.str)
+}
+
+func appendProtoAttributes(buf []byte, field int, attrs []keyValue) []byte {
+	for _, kv := range attrs {
+		buf = appendMessage(buf, field, func(b []byte) []byte {
+			b = appendStringField(b, 1, kv.key)
+			return appendMessage(b, 2, func(b []byte) []byte {
+				return appendProtoValue(b, kv.value)
+			})
+		})
+	}
+	return buf
+}
+
+func appendProtoRecord(buf []byte, r *record) []byte {
+	buf = appendTag(buf, 1, wireFixed64)
+	buf = binary.LittleEndian.AppendUint64(buf, r.time)
+	if r.severityNumber != 0 {
+		buf = appendTag(buf, 2, wireVarint)
+		buf = appendVarint(buf, uint64(r.severityNumber))
+	}
+	if r.severityText != "" {
+		buf = appendStringField(buf, 3, r.severityText)
+	}
+	buf = appendMessage(buf, 5, func(b []byte) []byte {
+		return appendProtoValue(b, value{str: r.body})
+	})
+	buf = appendProtoAttributes(buf, 6, r.attributes)
+	if r.flags != 0 {
+		buf = appendTag(buf, 8, wireFixed32)
+		buf = binary.LittleEndian.AppendUint32(buf, r.flags)
+	}
+	if r.traceID != nil {
+		buf = appendBytesField(buf, 9, r.traceID)
+	}
+	if r.spanID != nil {
+		buf = appendBytesField(buf, 10, r.spanID)
+	}
+	buf = appendTag(buf, 11, wireFixed64)
+	return binary.LittleEndian.AppendUint64(buf, r.observedTime)
+}
+
+// encodeProto encodes an ExportLogsServiceRequest in the OTLP/protobuf
+// format.
+func (x *Exporter) encodeProto(buf []byte, records []record) []byte {
+	return appendMessage(buf, 1, func(b []byte) []byte { // ResourceLogs
+		b = appendMessage(b, 1, func(b []byte) []byte { // Resource
+			return appendProtoAttributes(b, 1, x.resource)
+		})
+		return appendMessage(b, 2, func(b []byte) []byte { // ScopeLogs
+			b = appendMessage(b, 1, func(b []byte) []byte { // InstrumentationScope
+				return appendStringField(b, 1, x.scopeName)
+			})
+			for i := range records {
+				b = appendMessage(b, 2, func(b []byte) []byte {
+					return appendProtoRecord(b, &records[i])
+				})
+			}
+			return b
+		})
+	})
+}
@@ -0,0 +1,389 @@
+/*
+Package otlp provides an alog.Sink that exports log entries to an
+OpenTelemetry collector using OTLP/HTTP.
+
+Entries are converted to OTLP LogRecords: the entry's level is mapped to the
+severity number and text, its message to the body, and its fields to
+attributes.  The trace_id, span_id, and trace_flags fields, as added by the
+otelalog package, are mapped to the record's trace context.  Records are sent
+in batches, encoded as OTLP/JSON or OTLP/protobuf.
+
+	exporter, err := otlp.NewExporter(otlp.Config{
+		Endpoint: "http://localhost:4318/v1/logs",
+		Resource: alog.Fields{"service.name": "myapp"},
+	})
+	if err != nil {
+		return err
+	}
+	defer exporter.Close()
+	logger := alog.NewMulti([]alog.SinkConfig{{Sink: exporter, Level: alog.InfoLevel}})
+	defer logger.Close()
+
+Close the Logger before the Exporter, so that all entries are exported.
+*/
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gammazero/alog"
+)
+
+// Encoding is the encoding of the OTLP requests sent to the collector.
+type Encoding int
+
+const (
+	// JSON encodes requests as OTLP/JSON.
+	JSON Encoding = iota
+	// Protobuf encodes requests as OTLP/protobuf.
+	Protobuf
+)
+
+// DefaultEndpoint is the OTLP/HTTP logs endpoint of a local collector.
+const DefaultEndpoint = "http://localhost:4318/v1/logs"
+
+// Config configures an Exporter.  Zero values select the defaults.
+type Config struct {
+	// Endpoint is the URL that requests are POSTed to.  The default is
+	// DefaultEndpoint.
+	Endpoint string
+
+	// Encoding is the encoding of requests.  The default is JSON.
+	Encoding Encoding
+
+	// Headers are added to each request, such as for authentication.
+	Headers map[string]string
+
+	// Resource holds the attributes of the resource that produces the logs.
+	// If it has no "service.name" attribute, then the service name is
+	// "unknown_service:" followed by the name of the executable.
+	Resource alog.Fields
+
+	// ScopeName is the name of the instrumentation scope of the records.
+	// The default is "github.com/gammazero/alog".
+	ScopeName string
+
+	// BatchSize is the number of records that causes a batch to be sent
+	// before FlushInterval has passed.  The default is 512.
+	BatchSize int
+
+	// FlushInterval is the longest time that a record waits to be sent.  The
+	// default is 1 second.
+	FlushInterval time.Duration
+
+	// MaxPending is the largest number of records waiting to be sent.  When
+	// there are MaxPending records, because the collector cannot be reached,
+	// new entries are dropped and alog.ErrQueueFull is returned.  The
+	// default is 8 times BatchSize.
+	MaxPending int
+
+	// MaxRetries is the number of times a failed request is retried.  The
+	// default is 5.  Set to a negative value to not retry.
+	MaxRetries int
+
+	// InitialBackoff is the time to wait before the first retry.  The wait
+	// doubles after each retry, up to MaxBackoff.  A Retry-After header in
+	// the collector's response overrides the wait.  The defaults are 500
+	// milliseconds and 30 seconds.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Client is the HTTP client used to send requests.  The default is a
+	// client with a 10 second timeout.
+	Client *http.Client
+
+	// CloseTimeout is the longest time that Close waits for requests to be
+	// sent, including requests that were being sent when Close was called.
+	// Requests that are not complete by then are canceled, and their
+	// records are dropped.  The default is 5 seconds.
+	CloseTimeout time.Duration
+}
+
+// Exporter is an alog.Sink that sends entries to an OTLP/HTTP collector.
+// Entries are sent in batches by a separate goroutine.  An error exporting a
+// batch is returned by the next call to WriteEntry.
+type Exporter struct {
+	cfg       Config
+	resource  []keyValue
+	scopeName string
+
+	mutex    sync.Mutex
+	pending  []record
+	err      error
+	closeErr error
+	flushCh  chan chan error
+	wakeCh   chan struct{}
+	quit     chan struct{}
+	done     chan struct{}
+	closed   bool
+
+	// ctx is the context of requests, which is canceled when Close has
+	// waited for CloseTimeout.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewExporter creates an Exporter and starts its goroutine.
+func NewExporter(cfg Config) (*Exporter, error) {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultEndpoint
+	}
+	if cfg.Encoding != JSON && cfg.Encoding != Protobuf {
+		return nil, fmt.Errorf("otlp: invalid encoding %d", int(cfg.Encoding))
+	}
+	if cfg.ScopeName == "" {
+		cfg.ScopeName = "github.com/gammazero/alog"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 8 * cfg.BatchSize
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.CloseTimeout <= 0 {
+		cfg.CloseTimeout = 5 * time.Second
+	}
+
+	resource := make(alog.Fields, len(cfg.Resource)+1)
+	for k, v := range cfg.Resource {
+		resource[k] = v
+	}
+	if _, ok := resource["service.name"]; !ok {
+		resource["service.name"] = "unknown_service:" + filepath.Base(os.Args[0])
+	}
+
+	x := &Exporter{
+		cfg:       cfg,
+		resource:  toAttributes(resource),
+		scopeName: cfg.ScopeName,
+		flushCh:   make(chan chan error),
+		wakeCh:    make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	x.ctx, x.cancel = context.WithCancel(context.Background())
+	go x.run()
+	return x, nil
+}
+
+// WriteEntry adds the entry to the batch of records to send.  It returns the
+// error from exporting a previous batch, if there was one.
+func (x *Exporter) WriteEntry(e *alog.Entry) error {
+	r := newRecord(e, time.Now())
+	x.mutex.Lock()
+	if x.closed {
+		x.mutex.Unlock()
+		return errors.New("otlp: exporter is closed")
+	}
+	err := x.err
+	x.err = nil
+	if len(x.pending) >= x.cfg.MaxPending {
+		x.mutex.Unlock()
+		if err == nil {
+			err = alog.ErrQueueFull
+		}
+		return err
+	}
+	x.pending = append(x.pending, r)
+	full := len(x.pending) >= x.cfg.BatchSize
+	x.mutex.Unlock()
+
+	if full {
+		select {
+		case x.wakeCh <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// Flush sends all pending records, and returns any error from sending them.
+func (x *Exporter) Flush() error {
+	errCh := make(chan error)
+	select {
+	case x.flushCh <- errCh:
+		return <-errCh
+	case <-x.done:
+		return errors.New("otlp: exporter is closed")
+	}
+}
+
+// Close sends all pending records and stops the Exporter's goroutine.  It
+// returns any error from sending the records.  Failed requests are not
+// retried once Close is called, so that Close does not wait for the backoff
+// of retries, and requests still being sent after CloseTimeout are canceled.
+func (x *Exporter) Close() error {
+	x.mutex.Lock()
+	if x.closed {
+		x.mutex.Unlock()
+		return nil
+	}
+	x.closed = true
+	x.mutex.Unlock()
+
+	close(x.quit)
+	timer := time.AfterFunc(x.cfg.CloseTimeout, x.cancel)
+	<-x.done
+	timer.Stop()
+	x.cancel()
+	return x.closeErr
+}
+
+func (x *Exporter) run() {
+	defer close(x.done)
+	ticker := time.NewTicker(x.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-x.quit:
+			x.closeErr = x.exportAll()
+			return
+		case errCh := <-x.flushCh:
+			errCh <- x.exportAll()
+		case <-x.wakeCh:
+			x.setErr(x.exportAll())
+		case <-ticker.C:
+			x.setErr(x.exportAll())
+		}
+	}
+}
+
+func (x *Exporter) setErr(err error) {
+	if err == nil {
+		return
+	}
+	x.mutex.Lock()
+	x.err = err
+	x.mutex.Unlock()
+}
+
+// exportAll sends the pending records in batches of at most BatchSize.  A
+// batch that cannot be sent is dropped.
+func (x *Exporter) exportAll() error {
+	var firstErr error
+	for {
+		x.mutex.Lock()
+		n := len(x.pending)
+		if n > x.cfg.BatchSize {
+			n = x.cfg.BatchSize
+		}
+		batch := make([]record, n)
+		copy(batch, x.pending)
+		x.pending = x.pending[n:]
+		if len(x.pending) == 0 {
+			x.pending = nil
+		}
+		x.mutex.Unlock()
+		if n == 0 {
+			return firstErr
+		}
+		if err := x.export(batch); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+}
+
+// export sends a batch, retrying with backoff if the request fails in a way
+// that may succeed later, unless the Exporter is closed.
+func (x *Exporter) export(batch []record) error {
+	var body []byte
+	contentType := "application/json"
+	if x.cfg.Encoding == Protobuf {
+		body = x.encodeProto(nil, batch)
+		contentType = "application/x-protobuf"
+	} else {
+		body = x.encodeJSON(nil, batch)
+	}
+
+	backoff := x.cfg.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := x.send(body, contentType)
+		if err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) || attempt >= x.cfg.MaxRetries {
+			return fmt.Errorf("otlp: dropped %d records: %w", len(batch), err)
+		}
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-x.quit:
+			timer.Stop()
+			return fmt.Errorf("otlp: dropped %d records: %w", len(batch), err)
+		}
+		if backoff *= 2; backoff > x.cfg.MaxBackoff {
+			backoff = x.cfg.MaxBackoff
+		}
+	}
+}
+
+// permanentError is an error response that is not retried.
+type permanentError struct {
+	status string
+}
+
+func (e *permanentError) Error() string {
+	return "collector responded " + e.status
+}
+
+// send sends one request.  If the request fails with a response that asks to
+// retry later, the time to wait is returned with the error.
+func (x *Exporter) send(body []byte, contentType string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(x.ctx, http.MethodPost, x.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, &permanentError{status: err.Error()}
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range x.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	rsp, err := x.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, rsp.Body)
+	rsp.Body.Close()
+
+	switch rsp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return 0, nil
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		var retryAfter time.Duration
+		if secs, err := strconv.Atoi(rsp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		return retryAfter, fmt.Errorf("collector responded %s", rsp.Status)
+	}
+	return 0, &permanentError{status: rsp.Status}
+}
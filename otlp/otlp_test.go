@@ -0,0 +1,367 @@
+package otlp
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gammazero/alog"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type collector struct {
+	mutex        sync.Mutex
+	bodies       [][]byte
+	contentTypes []string
+	failures     int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.failures > 0 {
+		c.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	c.bodies = append(c.bodies, body)
+	c.contentTypes = append(c.contentTypes, r.Header.Get("Content-Type"))
+}
+
+func TestSeverityNumber(t *testing.T) {
+	for level, want := range map[alog.Level]int{
+		alog.NoLevel:    0,
+		alog.PanicLevel: 24,
+		alog.FatalLevel: 21,
+		alog.ErrorLevel: 17,
+		alog.WarnLevel:  13,
+		alog.Level(45):  10,
+		alog.InfoLevel:  9,
+		alog.DebugLevel: 5,
+		alog.TraceLevel: 1,
+		alog.Level(99):  1,
+	} {
+		if got := severityNumber(level); got != want {
+			t.Errorf("level %d: expected severity %d, got %d", int(level), want, got)
+		}
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	c := &collector{failures: 2}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	x, err := NewExporter(Config{
+		Endpoint:       srv.URL,
+		Resource:       alog.Fields{"service.name": "test"},
+		FlushInterval:  time.Hour,
+		InitialBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := alog.NewMulti([]alog.SinkConfig{{Sink: x, Level: alog.DebugLevel}})
+	lg.WithFields(alog.Fields{
+		"user":        "rick",
+		"count":       3,
+		"ok":          true,
+		"trace_id":    "0102030405060708090a0b0c0d0e0f10",
+		"span_id":     "0102030405060708",
+		"trace_flags": "01",
+	}).Warn("portal unstable")
+	lg.Debug("second")
+	lg.Close()
+	// Flush retries after the failures.  Close does not retry.
+	if err = x.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err = x.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.bodies) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(c.bodies))
+	}
+	if c.contentTypes[0] != "application/json" {
+		t.Error("wrong content type:", c.contentTypes[0])
+	}
+	var req struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string
+					Value map[string]interface{}
+				}
+			}
+			ScopeLogs []struct {
+				Scope      struct{ Name string }
+				LogRecords []struct {
+					TimeUnixNano   string
+					SeverityNumber int
+					SeverityText   string
+					Body           map[string]interface{}
+					Attributes     []struct {
+						Key   string
+						Value map[string]interface{}
+					}
+					TraceID string `json:"traceId"`
+					SpanID  string `json:"spanId"`
+					Flags   int
+				}
+			}
+		}
+	}
+	if err = json.Unmarshal(c.bodies[0], &req); err != nil {
+		t.Fatalf("invalid JSON: %s: %s", err, c.bodies[0])
+	}
+	rl := req.ResourceLogs[0]
+	if len(rl.Resource.Attributes) != 1 || rl.Resource.Attributes[0].Value["stringValue"] != "test" {
+		t.Error("wrong resource:", rl.Resource)
+	}
+	if rl.ScopeLogs[0].Scope.Name != "github.com/gammazero/alog" {
+		t.Error("wrong scope:", rl.ScopeLogs[0].Scope)
+	}
+	records := rl.ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatal("expected 2 records, got", len(records))
+	}
+	r := records[0]
+	if r.SeverityNumber != 13 || r.SeverityText != "warn" || r.Body["stringValue"] != "portal unstable" {
+		t.Error("wrong record:", r)
+	}
+	if r.TraceID != "0102030405060708090a0b0c0d0e0f10" || r.SpanID != "0102030405060708" || r.Flags != 1 {
+		t.Error("wrong trace context:", r.TraceID, r.SpanID, r.Flags)
+	}
+	attrs := map[string]map[string]interface{}{}
+	for _, kv := range r.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if len(attrs) != 3 || attrs["user"]["stringValue"] != "rick" ||
+		attrs["count"]["intValue"] != "3" || attrs["ok"]["boolValue"] != true {
+		t.Error("wrong attributes:", attrs)
+	}
+	if records[1].SeverityNumber != 5 || records[1].TimeUnixNano == "" {
+		t.Error("wrong record:", records[1])
+	}
+}
+
+func TestExportRetryFails(t *testing.T) {
+	c := &collector{failures: 10}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	x, err := NewExporter(Config{
+		Endpoint:       srv.URL,
+		MaxRetries:     2,
+		InitialBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.WriteEntry(&alog.Entry{Time: time.Now(), Level: alog.InfoLevel, Message: "lost"})
+	if err = x.Flush(); err == nil {
+		t.Error("expected error after retries")
+	}
+	c.mutex.Lock()
+	if c.failures != 7 {
+		t.Error("expected 3 attempts, got", 10-c.failures)
+	}
+	c.mutex.Unlock()
+	x.Close()
+}
+
+func TestExportProtobuf(t *testing.T) {
+	c := new(collector)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	x, err := NewExporter(Config{
+		Endpoint:  srv.URL,
+		Encoding:  Protobuf,
+		BatchSize: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Unix(1700000000, 5)
+	if err = x.WriteEntry(&alog.Entry{
+		Time:    ts,
+		Level:   alog.ErrorLevel,
+		Message: "boom",
+		Fields:  alog.Fields{"ratio": 0.5},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = x.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.bodies) != 1 || c.contentTypes[0] != "application/x-protobuf" {
+		t.Fatal("expected 1 protobuf request")
+	}
+
+	// ExportLogsServiceRequest.resource_logs[0].scope_logs[0].log_records[0]
+	rl := field(t, c.bodies[0], 1)
+	sl := field(t, rl, 2)
+	lr := field(t, sl, 2)
+	if got := fixed64(t, lr, 1); got != uint64(ts.UnixNano()) {
+		t.Error("wrong time:", got)
+	}
+	if got := varint(t, lr, 2); got != 17 {
+		t.Error("wrong severity number:", got)
+	}
+	if got := string(field(t, lr, 3)); got != "error" {
+		t.Error("wrong severity text:", got)
+	}
+	if got := string(field(t, field(t, lr, 5), 1)); got != "boom" {
+		t.Error("wrong body:", got)
+	}
+	kv := field(t, lr, 6)
+	if got := string(field(t, kv, 1)); got != "ratio" {
+		t.Error("wrong attribute key:", got)
+	}
+	if got := math.Float64frombits(fixed64(t, field(t, kv, 2), 4)); got != 0.5 {
+		t.Error("wrong attribute value:", got)
+	}
+	resource := field(t, rl, 1)
+	attr := field(t, resource, 1)
+	if got := string(field(t, attr, 1)); got != "service.name" {
+		t.Error("wrong resource attribute:", got)
+	}
+}
+
+// find returns the raw value of the first occurrence of a field in a
+// protobuf message.
+func find(t *testing.T, msg []byte, num protowire.Number, typ protowire.Type) []byte {
+	t.Helper()
+	for len(msg) > 0 {
+		n, wt, tagLen := protowire.ConsumeTag(msg)
+		if tagLen < 0 {
+			t.Fatal("invalid tag")
+		}
+		msg = msg[tagLen:]
+		valLen := protowire.ConsumeFieldValue(n, wt, msg)
+		if valLen < 0 {
+			t.Fatal("invalid field value")
+		}
+		if n == num && wt == typ {
+			return msg[:valLen]
+		}
+		msg = msg[valLen:]
+	}
+	t.Fatalf("field %d not found", num)
+	return nil
+}
+
+func field(t *testing.T, msg []byte, num protowire.Number) []byte {
+	t.Helper()
+	b, _ := protowire.ConsumeBytes(find(t, msg, num, protowire.BytesType))
+	return b
+}
+
+func varint(t *testing.T, msg []byte, num protowire.Number) uint64 {
+	t.Helper()
+	v, _ := protowire.ConsumeVarint(find(t, msg, num, protowire.VarintType))
+	return v
+}
+
+func fixed64(t *testing.T, msg []byte, num protowire.Number) uint64 {
+	t.Helper()
+	v, _ := protowire.ConsumeFixed64(find(t, msg, num, protowire.Fixed64Type))
+	return v
+}
+
+func TestCloseDuringBackoff(t *testing.T) {
+	c := &collector{failures: 100}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	x, err := NewExporter(Config{
+		Endpoint:       srv.URL,
+		InitialBackoff: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.WriteEntry(&alog.Entry{Time: time.Now(), Level: alog.InfoLevel, Message: "stuck"})
+	flushed := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { flushed <- x.Flush() }()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	closed := make(chan error)
+	go func() { closed <- x.Close() }()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for retry backoff")
+	}
+	// The Flush that was retrying returns the error of dropping the record.
+	// The other Flush, if it was not already waiting, finds nothing to send
+	// or that the Exporter is closed.
+	var errs int
+	for i := 0; i < 2; i++ {
+		if err := <-flushed; err != nil {
+			errs++
+		}
+	}
+	if errs == 0 {
+		t.Error("expected Flush error")
+	}
+}
+
+func TestUintValues(t *testing.T) {
+	for v, want := range map[interface{}]value{
+		uint(7):                {kind: intValue, num: 7},
+		uint64(math.MaxInt64):  {kind: intValue, num: math.MaxInt64},
+		uint64(math.MaxUint64): {kind: stringValue, str: "18446744073709551615"},
+		uintptr(9):             {kind: intValue, num: 9},
+	} {
+		if got := toValue(v); got != want {
+			t.Errorf("wrong value of %T %v: %+v", v, v, got)
+		}
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	x, err := NewExporter(Config{
+		Endpoint:     srv.URL,
+		CloseTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.WriteEntry(&alog.Entry{Time: time.Now(), Level: alog.InfoLevel, Message: "stuck"})
+	flushed := make(chan error, 1)
+	go func() { flushed <- x.Flush() }()
+	time.Sleep(50 * time.Millisecond)
+
+	closed := make(chan error)
+	go func() { closed <- x.Close() }()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for request")
+	}
+	// The request that was being sent is canceled and its record dropped.
+	if err = <-flushed; err == nil {
+		t.Error("expected Flush error")
+	}
+}
@@ -0,0 +1,142 @@
+package alog
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type siteKind uint8
+
+const (
+	siteOnce siteKind = iota
+	siteEveryN
+	siteEvery
+	siteRate
+)
+
+// siteKey identifies the state of a rate limiting helper at a callsite.  The
+// number of sites is limited by the number of callsites in the program.
+type siteKey struct {
+	kind siteKind
+	pc   uintptr
+}
+
+type siteState struct {
+	mutex  sync.Mutex
+	done   bool
+	count  uint64
+	last   time.Time
+	tokens float64
+}
+
+// site returns the state for the key, creating it if needed.  State is kept
+// by the root logger, so that Loggers created for each log call, as in
+// lg.EveryN(10).Info(...), share the state of their callsite.
+func (a *logger) site(key siteKey) *siteState {
+	if s, ok := a.sites.Load(key); ok {
+		return s.(*siteState)
+	}
+	s, _ := a.sites.LoadOrStore(key, &siteState{})
+	return s.(*siteState)
+}
+
+// maxOnceKeys is the number of keys given to Once that a Logger remembers.
+// When more keys are used, the least recently used key is forgotten, so that
+// keys such as request IDs do not grow memory without bound.
+const maxOnceKeys = 4096
+
+// onceKeys is the set of keys given to Once that have logged an entry.
+type onceKeys struct {
+	mutex sync.Mutex
+	order *list.List // of string, most recently used first
+	keys  map[string]*list.Element
+}
+
+// add records that the key logged an entry, and returns false if the key
+// already logged an entry.
+func (o *onceKeys) add(key string) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if e, ok := o.keys[key]; ok {
+		o.order.MoveToFront(e)
+		return false
+	}
+	if o.keys == nil {
+		o.order = list.New()
+		o.keys = make(map[string]*list.Element)
+	} else if len(o.keys) >= maxOnceKeys {
+		oldest := o.order.Back()
+		o.order.Remove(oldest)
+		delete(o.keys, oldest.Value.(string))
+	}
+	o.keys[key] = o.order.PushFront(key)
+	return true
+}
+
+func (a *logger) onceGate(key string) func(ent *entry) bool {
+	return func(ent *entry) bool {
+		if key != "" {
+			return a.onceKeys.add(key)
+		}
+		s := a.site(siteKey{kind: siteOnce, pc: ent.pc})
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.done {
+			return false
+		}
+		s.done = true
+		return true
+	}
+}
+
+func (a *logger) everyNGate(n int) func(ent *entry) bool {
+	if n < 1 {
+		n = 1
+	}
+	return func(ent *entry) bool {
+		s := a.site(siteKey{kind: siteEveryN, pc: ent.pc})
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.count++
+		return (s.count-1)%uint64(n) == 0
+	}
+}
+
+func (a *logger) everyGate(interval time.Duration) func(ent *entry) bool {
+	return func(ent *entry) bool {
+		s := a.site(siteKey{kind: siteEvery, pc: ent.pc})
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if !s.last.IsZero() && ent.ts.Sub(s.last) < interval {
+			return false
+		}
+		s.last = ent.ts
+		return true
+	}
+}
+
+func (a *logger) rateLimitGate(rate float64, burst int) func(ent *entry) bool {
+	if burst < 1 {
+		burst = 1
+	}
+	return func(ent *entry) bool {
+		s := a.site(siteKey{kind: siteRate, pc: ent.pc})
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.last.IsZero() {
+			s.tokens = float64(burst)
+		} else if elapsed := ent.ts.Sub(s.last); elapsed > 0 {
+			s.tokens += elapsed.Seconds() * rate
+			if s.tokens > float64(burst) {
+				s.tokens = float64(burst)
+			}
+		}
+		s.last = ent.ts
+		if s.tokens < 1 {
+			return false
+		}
+		s.tokens--
+		return true
+	}
+}
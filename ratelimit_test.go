@@ -0,0 +1,76 @@
+package alog
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnceAndEveryN(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, DebugLevel, " ", "")
+
+	for i := 0; i < 7; i++ {
+		lg.EveryN(3).Infof("every %d", i)
+		lg.Once("").Info("once by callsite")
+		lg.WithField("i", i).Once("startup").Info("once by key")
+	}
+	lg.Once("startup").Info("same key")
+	lg.Close()
+
+	expect := []string{
+		" INFO every 0",
+		" INFO once by callsite",
+		" INFO once by key (i=0)",
+		" INFO every 3",
+		" INFO every 6",
+	}
+	checkLines(t, buf, expect...)
+}
+
+func TestEveryAndRateLimited(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, DebugLevel, " ", "")
+
+	every := lg.Every(time.Hour)
+	for i := 0; i < 5; i++ {
+		every.Info("every hour")
+	}
+	for i := 0; i < 5; i++ {
+		lg.RateLimited(0.001, 2).Warn("limited")
+	}
+	lg.Close()
+
+	out := buf.String()
+	if n := strings.Count(out, "every hour"); n != 1 {
+		t.Errorf("expected 1 entry logged every hour, got %d", n)
+	}
+	if n := strings.Count(out, "limited"); n != 2 {
+		t.Errorf("expected 2 rate limited entries, got %d", n)
+	}
+}
+
+func TestOnceKeysBounded(t *testing.T) {
+	var o onceKeys
+	if !o.add("first") || o.add("first") {
+		t.Fatal("key not remembered")
+	}
+	for i := 0; i < maxOnceKeys; i++ {
+		o.add(strconv.Itoa(i))
+		if i == maxOnceKeys/2 {
+			// Using a key keeps it from being forgotten.
+			o.add("first")
+		}
+	}
+	if len(o.keys) != maxOnceKeys || o.order.Len() != maxOnceKeys {
+		t.Fatal("wrong number of keys:", len(o.keys))
+	}
+	if o.add("first") {
+		t.Error("recently used key was forgotten")
+	}
+	if !o.add("0") {
+		t.Error("least recently used key was not forgotten")
+	}
+}
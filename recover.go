@@ -0,0 +1,50 @@
+package alog
+
+import "runtime/debug"
+
+// StackField is the field that Recover and RecoverAndContinue set to the stack
+// of the panicking goroutine.
+const StackField = "stack"
+
+// Each Recover method calls recover() itself, since recover only stops a panic
+// when called directly by a deferred function, and calls emit directly, so
+// that the entry has the same call depth as the entries of other methods.
+
+func (a *logger) Recover() {
+	if r := recover(); r != nil {
+		a.root.withStack().emit(PanicLevel, "panic: %v", []interface{}{r}, false)
+		a.Flush()
+		panic(r)
+	}
+}
+
+func (a *logger) RecoverAndContinue() {
+	if r := recover(); r != nil {
+		a.root.withStack().emit(PanicLevel, "panic: %v", []interface{}{r}, false)
+		a.Flush()
+	}
+}
+
+func (f *fieldLogger) Recover() {
+	if r := recover(); r != nil {
+		f.withStack().emit(PanicLevel, "panic: %v", []interface{}{r}, false)
+		f.Flush()
+		panic(r)
+	}
+}
+
+func (f *fieldLogger) RecoverAndContinue() {
+	if r := recover(); r != nil {
+		f.withStack().emit(PanicLevel, "panic: %v", []interface{}{r}, false)
+		f.Flush()
+	}
+}
+
+// withStack returns a fieldLogger that has the fieldLogger's fields and the
+// StackField field.  The stack is only captured if PanicLevel is logable.
+func (f *fieldLogger) withStack() *fieldLogger {
+	if !f.LogableAt(PanicLevel) {
+		return f
+	}
+	return f.WithField(StackField, string(debug.Stack())).(*fieldLogger)
+}
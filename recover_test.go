@@ -0,0 +1,63 @@
+package alog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	buf := new(lockedBuffer)
+	lg := NewJSON(buf, InfoLevel, "")
+	defer lg.Close()
+
+	func() {
+		defer lg.WithField("job", 7).RecoverAndContinue()
+		var m map[string]int
+		m["boom"] = 1
+	}()
+	// Flush has written the entry before RecoverAndContinue returned.
+	out := buf.String()
+	for _, want := range []string{`"level":"panic"`, `"msg":"panic: assignment to entry in nil map"`,
+		`"job":7`, `"stack":"goroutine `, "TestRecover"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in %s", want, out)
+		}
+	}
+
+	var repanicked interface{}
+	func() {
+		defer func() { repanicked = recover() }()
+		defer lg.Recover()
+		panic("again")
+	}()
+	if repanicked != "again" {
+		t.Error("expected panic to continue, got", repanicked)
+	}
+	if !strings.Contains(buf.String(), `"msg":"panic: again"`) {
+		t.Error("panic not logged:", buf.String())
+	}
+
+	// Without a panic, nothing is logged.
+	before := buf.String()
+	func() {
+		defer lg.Recover()
+	}()
+	lg.Flush()
+	if buf.String() != before {
+		t.Error("logged without a panic")
+	}
+}
+
+func TestFlush(t *testing.T) {
+	buf := new(lockedBuffer)
+	lg := NewMulti([]SinkConfig{{Sink: NewSink(buf, NewTextFormatter("", "")), Level: InfoLevel, QueueSize: 10}})
+	defer lg.Close()
+	for i := 0; i < 5; i++ {
+		lg.Info("queued")
+	}
+	lg.Flush()
+	if n := bytes.Count([]byte(buf.String()), []byte("queued")); n != 5 {
+		t.Errorf("expected 5 entries after Flush, got %d", n)
+	}
+}
@@ -0,0 +1,420 @@
+/*
+Package rotate provides an io.Writer that writes to a set of log files,
+rotating to a new file when the current one reaches a maximum size, or at
+hourly or daily time boundaries.
+
+A Writer is meant to be the output of an alog.Logger:
+
+	w := &rotate.Writer{
+		Filename:    "/var/log/myapp/app.log",
+		MaxSize:     100 << 20,
+		Interval:    rotate.Daily,
+		MaxBackups:  7,
+		Compress:    true,
+		CurrentLink: "/var/log/myapp/current",
+	}
+	logger := alog.NewText(w, alog.InfoLevel, "", "")
+	...
+	logger.Close()
+	w.Close()
+
+The alog.Logger makes a single call to Write for each entry, from its
+asynchronous writer goroutine.  A Writer only rotates before writing the data
+given to Write, so rotation happens between entries and never splits an entry
+across files.
+
+Each log file is named by inserting the time it was created, formatted using
+TimeFormat, between the base name and extension of Filename.  For example, with
+the Filename above, a file might be named "app-2006-01-02T15-04-05.000.log".
+Log files are never renamed, so that CurrentLink always points to the file
+being written.
+*/
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Interval specifies a time boundary at which to rotate files.
+type Interval int
+
+const (
+	// Never does not rotate at time boundaries.
+	Never Interval = iota
+	// Hourly rotates at the start of every hour.
+	Hourly
+	// Daily rotates at midnight, local time.
+	Daily
+)
+
+// DefaultTimeFormat is the layout used to format the time in file names when
+// Writer.TimeFormat is not set.
+const DefaultTimeFormat = "2006-01-02T15-04-05.000"
+
+const compressSuffix = ".gz"
+
+// currentTime is replaced in tests.
+var currentTime = time.Now
+
+// Writer is an io.WriteCloser that writes to rotating log files.  The zero
+// value is not usable; Filename must be set.  Configuration fields must not
+// be changed after the first call to Write.
+type Writer struct {
+	// Filename determines the directory, base name, and extension of the log
+	// files.  The directory is created if it does not exist.
+	Filename string
+
+	// TimeFormat is the time.Format layout used to put the file's creation
+	// time into its name.  If not set, DefaultTimeFormat is used.
+	TimeFormat string
+
+	// MaxSize is the maximum size, in bytes, of a log file.  The file is
+	// rotated before a write that would exceed this size.  Zero means there
+	// is no maximum size.
+	MaxSize int64
+
+	// Interval specifies the time boundary at which files are rotated.
+	Interval Interval
+
+	// MaxBackups is the maximum number of old log files to keep.  Zero means
+	// all old files are kept, subject to MaxAge.
+	MaxBackups int
+
+	// MaxAge is the maximum age of old log files to keep, based on their
+	// modification time.  Zero means files are not removed due to age.
+	MaxAge time.Duration
+
+	// Compress determines if old log files are compressed using gzip.
+	// Compression happens in the background after rotation.
+	Compress bool
+
+	// CurrentLink, if set, is the path of a symlink that is maintained to
+	// point to the file currently being written.
+	CurrentLink string
+
+	mutex      sync.Mutex
+	file       *os.File
+	name       string
+	size       int64
+	nextRotate time.Time
+
+	millOnce sync.Once
+	millCh   chan struct{}
+	millDone chan struct{}
+}
+
+var _ io.WriteCloser = (*Writer)(nil)
+
+// Write writes p to the current log file, first rotating the file if writing
+// p would exceed MaxSize, or if a time boundary has been crossed since the
+// file was created.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		if err := w.openNew(); err != nil {
+			return 0, err
+		}
+		// Process old files left from before the Writer was opened.
+		w.startMill()
+	} else if w.needRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current log file and opens a new one.
+func (w *Writer) Rotate() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.rotate()
+}
+
+// Close closes the current log file, and waits for any background
+// compression and cleanup of old files to finish.  A Write after Close opens a
+// new log file, but old files are no longer compressed or removed.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	millCh := w.millCh
+	w.millCh = nil
+	w.mutex.Unlock()
+
+	if millCh != nil {
+		close(millCh)
+		<-w.millDone
+	}
+	return err
+}
+
+func (w *Writer) needRotate(size int64) bool {
+	if w.MaxSize > 0 && w.size > 0 && w.size+size > w.MaxSize {
+		return true
+	}
+	return !w.nextRotate.IsZero() && !currentTime().Before(w.nextRotate)
+}
+
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	if err := w.openNew(); err != nil {
+		return err
+	}
+	w.startMill()
+	return nil
+}
+
+// openNew creates a new log file named for the current time, and updates
+// CurrentLink to point to it.
+func (w *Writer) openNew() error {
+	dir := filepath.Dir(w.Filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create log directory: %w", err)
+	}
+
+	now := currentTime()
+	prefix, ext := w.prefixAndExt()
+	name := filepath.Join(dir, prefix+now.Format(w.timeFormat())+ext)
+	// If a file with the same name exists, because files are rotated more
+	// often than the TimeFormat resolution, add a sequence number.
+	for seq := 1; ; seq++ {
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			break
+		}
+		name = filepath.Join(dir, fmt.Sprintf("%s%s.%d%s", prefix,
+			now.Format(w.timeFormat()), seq, ext))
+	}
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open new log file: %w", err)
+	}
+	w.file = f
+	w.name = name
+	w.size = 0
+	w.nextRotate = nextBoundary(now, w.Interval)
+
+	if w.CurrentLink != "" {
+		if err = w.updateLink(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateLink atomically replaces CurrentLink with a symlink to target.
+func (w *Writer) updateLink(target string) error {
+	linkDir := filepath.Dir(w.CurrentLink)
+	if rel, err := filepath.Rel(linkDir, target); err == nil {
+		target = rel
+	}
+	tmpLink := w.CurrentLink + ".tmp"
+	os.Remove(tmpLink)
+	if err := os.Symlink(target, tmpLink); err != nil {
+		return fmt.Errorf("cannot create current link: %w", err)
+	}
+	if err := os.Rename(tmpLink, w.CurrentLink); err != nil {
+		os.Remove(tmpLink)
+		return fmt.Errorf("cannot replace current link: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) timeFormat() string {
+	if w.TimeFormat == "" {
+		return DefaultTimeFormat
+	}
+	return w.TimeFormat
+}
+
+// prefixAndExt returns the file name prefix, which precedes the time, and
+// the file extension.
+func (w *Writer) prefixAndExt() (string, string) {
+	base := filepath.Base(w.Filename)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// nextBoundary returns the time of the next rotation boundary after t, or the
+// zero time if not rotating at time boundaries.
+func nextBoundary(t time.Time, interval Interval) time.Time {
+	switch interval {
+	case Hourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+	case Daily:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+// ---- Background compression and removal of old files ----
+
+// startMill signals the mill goroutine to process old log files, starting
+// the goroutine if it is not already running.
+func (w *Writer) startMill() {
+	if !w.Compress && w.MaxBackups == 0 && w.MaxAge == 0 {
+		return
+	}
+	w.millOnce.Do(func() {
+		w.millCh = make(chan struct{}, 1)
+		w.millDone = make(chan struct{})
+		go w.millRun(w.millCh)
+	})
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Writer) millRun(millCh <-chan struct{}) {
+	defer close(w.millDone)
+	for range millCh {
+		w.millRunOnce()
+	}
+}
+
+// millRunOnce removes old log files beyond MaxBackups or older than MaxAge,
+// and compresses the remaining old files if Compress is set.
+func (w *Writer) millRunOnce() {
+	w.mutex.Lock()
+	current := w.name
+	w.mutex.Unlock()
+
+	files, err := w.oldFiles(current)
+	if err != nil {
+		return
+	}
+
+	var remove []os.FileInfo
+	if w.MaxBackups > 0 && len(files) > w.MaxBackups {
+		remove = files[w.MaxBackups:]
+		files = files[:w.MaxBackups]
+	}
+	if w.MaxAge > 0 {
+		cutoff := currentTime().Add(-w.MaxAge)
+		for len(files) != 0 && files[len(files)-1].ModTime().Before(cutoff) {
+			remove = append(remove, files[len(files)-1])
+			files = files[:len(files)-1]
+		}
+	}
+	dir := filepath.Dir(w.Filename)
+	for _, f := range remove {
+		os.Remove(filepath.Join(dir, f.Name()))
+	}
+	if w.Compress {
+		for _, f := range files {
+			if !strings.HasSuffix(f.Name(), compressSuffix) {
+				compressFile(filepath.Join(dir, f.Name()), f)
+			}
+		}
+	}
+}
+
+// oldFiles returns the log files, other than current, sorted newest first.
+func (w *Writer) oldFiles(current string) ([]os.FileInfo, error) {
+	dir := filepath.Dir(w.Filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	current = filepath.Base(current)
+	var files []os.FileInfo
+	for _, e := range entries {
+		name := e.Name()
+		if name == current || !e.Type().IsRegular() || !w.isLogFile(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		ti, tj := files[i].ModTime(), files[j].ModTime()
+		if ti.Equal(tj) {
+			return files[i].Name() > files[j].Name()
+		}
+		return ti.After(tj)
+	})
+	return files, nil
+}
+
+// isLogFile reports whether name is the name of a log file of the Writer, as
+// created by openNew, and possibly compressed.  The time in the name must
+// parse with TimeFormat, so that the files of a Writer whose base name starts
+// with the same prefix, such as "app-access.log" for "app.log", do not match.
+func (w *Writer) isLogFile(name string) bool {
+	prefix, ext := w.prefixAndExt()
+	name = strings.TrimSuffix(name, compressSuffix)
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+		return false
+	}
+	ts := name[len(prefix) : len(name)-len(ext)]
+	if _, err := time.ParseInLocation(w.timeFormat(), ts, time.Local); err == nil {
+		return true
+	}
+	// Try without a sequence number.
+	i := strings.LastIndexByte(ts, '.')
+	if i < 0 {
+		return false
+	}
+	if _, err := strconv.Atoi(ts[i+1:]); err != nil {
+		return false
+	}
+	_, err := time.ParseInLocation(w.timeFormat(), ts[:i], time.Local)
+	return err == nil
+}
+
+// compressFile gzips src into src+".gz", preserving its modification time,
+// and removes src.
+func compressFile(src string, info os.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	dst := src + compressSuffix
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	os.Chtimes(dst, info.ModTime(), info.ModTime())
+	return os.Remove(src)
+}
@@ -0,0 +1,138 @@
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "app-") {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+func TestRotateSize(t *testing.T) {
+	dir := t.TempDir()
+	w := &Writer{
+		Filename:    filepath.Join(dir, "app.log"),
+		MaxSize:     10,
+		MaxBackups:  1,
+		Compress:    true,
+		CurrentLink: filepath.Join(dir, "current"),
+	}
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files are written as [one two] [three] [four five], so there are 3
+	// files: current plus 2 old, of which 1 is kept.
+	names := logFiles(t, dir)
+	if len(names) != 2 {
+		t.Fatalf("expected 2 files, got %d: %q", len(names), names)
+	}
+	var compressed int
+	for _, name := range names {
+		if strings.HasSuffix(name, ".log.gz") {
+			compressed++
+		}
+	}
+	if compressed != 1 {
+		t.Error("expected 1 compressed file, got", compressed)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "four\nfive\n" {
+		t.Errorf("current link has wrong data: %q", data)
+	}
+}
+
+func TestRotateInterval(t *testing.T) {
+	now := time.Date(2020, 1, 1, 23, 59, 0, 0, time.Local)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = time.Now }()
+
+	dir := t.TempDir()
+	w := &Writer{
+		Filename:   filepath.Join(dir, "app.log"),
+		TimeFormat: "2006-01-02",
+		Interval:   Daily,
+	}
+	w.Write([]byte("day one\n"))
+	w.Write([]byte("still day one\n"))
+	now = now.Add(time.Minute)
+	w.Write([]byte("day two\n"))
+	w.Close()
+
+	names := logFiles(t, dir)
+	if len(names) != 2 || names[0] != "app-2020-01-01.log" || names[1] != "app-2020-01-02.log" {
+		t.Fatalf("wrong files: %q", names)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, names[0]))
+	if string(data) != "day one\nstill day one\n" {
+		t.Errorf("wrong data in first file: %q", data)
+	}
+}
+
+func TestOldFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{
+		"app-2020-01-01T00-00-00.000.log",
+		"app-2020-01-02T00-00-00.000.1.log.gz",
+		"app-2020-01-03T00-00-00.000.log",
+		"app-access-2020-01-01T00-00-00.000.log",
+		"app-notes.log",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, old, old)
+		old = old.Add(time.Minute)
+	}
+	w := &Writer{
+		Filename:   filepath.Join(dir, "app.log"),
+		MaxBackups: 1,
+	}
+	// Old files are removed when the Writer is opened, not only on rotation.
+	if _, err := w.Write([]byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names := logFiles(t, dir)
+	want := map[string]bool{
+		"app-2020-01-03T00-00-00.000.log":        true,
+		"app-access-2020-01-01T00-00-00.000.log": true,
+		"app-notes.log":                          true,
+	}
+	if len(names) != len(want)+1 {
+		t.Fatalf("wrong files: %q", names)
+	}
+	for _, name := range names {
+		if !want[name] && filepath.Join(dir, name) != w.name {
+			t.Errorf("unexpected file %s in %q", name, names)
+		}
+	}
+}
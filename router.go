@@ -0,0 +1,155 @@
+package alog
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+)
+
+// DefaultRoute is the name under which Router counts entries that do not
+// match any route.
+const DefaultRoute = "default"
+
+// Predicate reports whether a log entry matches some condition.
+type Predicate func(e *Entry) bool
+
+// FieldEquals returns a Predicate that matches entries having the field key
+// with a value that formats, in the manner of fmt.Sprint, as value.
+func FieldEquals(key, value string) Predicate {
+	return func(e *Entry) bool {
+		v, ok := e.Fields[key]
+		if !ok {
+			return false
+		}
+		if s, ok := v.(string); ok {
+			return s == value
+		}
+		return fmt.Sprint(v) == value
+	}
+}
+
+// LevelAtLeast returns a Predicate that matches entries at the given level or
+// a more severe level.  Entries without a level are not matched.
+func LevelAtLeast(level Level) Predicate {
+	level = legacyLevel(level)
+	return func(e *Entry) bool {
+		return e.Level != NoLevel && e.Level <= level
+	}
+}
+
+// MessageMatches returns a Predicate that matches entries whose message
+// matches the regular expression.
+func MessageMatches(re *regexp.Regexp) Predicate {
+	return func(e *Entry) bool {
+		return re.MatchString(e.Message)
+	}
+}
+
+// Route sends entries that match a Predicate to one of a Router's sinks.
+type Route struct {
+	// Name identifies the route in the Router's counts.  If empty, the name
+	// of the route's Sink is used.
+	Name string
+
+	// Match selects the entries to send to the Sink.
+	Match Predicate
+
+	// Sink is the name of the sink to send matching entries to.
+	Sink string
+
+	// Continue, if true, continues trying routes after this one matches, so
+	// that an entry may be sent to more than one sink.  Otherwise, an entry
+	// is only sent to the sink of the first route that matches it.
+	Continue bool
+}
+
+// Router is a Sink that dispatches each entry to one or more named child
+// sinks, according to its routes.  Routes are tried in order.  Entries that
+// do not match any route are sent to the default sink, if there is one.
+//
+// A Router counts the entries that match each route.  Entries that match no
+// route are counted as DefaultRoute.
+type Router struct {
+	routes       []route
+	defaultSink  Sink
+	defaultCount uint64
+}
+
+type route struct {
+	Route
+	sink  Sink
+	count uint64
+}
+
+// NewRouter creates a Router that dispatches entries to the named sinks.  The
+// defaultSink is the name of the sink that receives entries that match no
+// route.  If defaultSink is empty, these entries are discarded.
+//
+// The child sinks are written to by the goroutine that writes to the Router.
+// To give a child sink its own queue, give it its own Logger instead.
+func NewRouter(sinks map[string]Sink, routes []Route, defaultSink string) (*Router, error) {
+	r := &Router{
+		routes: make([]route, len(routes)),
+	}
+	for i, rt := range routes {
+		sink, ok := sinks[rt.Sink]
+		if !ok {
+			return nil, fmt.Errorf("route %d: unknown sink %q", i, rt.Sink)
+		}
+		if rt.Match == nil {
+			return nil, fmt.Errorf("route %d: missing predicate", i)
+		}
+		if rt.Name == "" {
+			rt.Name = rt.Sink
+		}
+		r.routes[i] = route{Route: rt, sink: sink}
+	}
+	if defaultSink != "" {
+		sink, ok := sinks[defaultSink]
+		if !ok {
+			return nil, fmt.Errorf("unknown default sink %q", defaultSink)
+		}
+		r.defaultSink = sink
+	}
+	return r, nil
+}
+
+// WriteEntry sends the entry to the sinks of the routes it matches, or to the
+// default sink.  If writing to any sink fails, the first error is returned.
+func (r *Router) WriteEntry(e *Entry) error {
+	var err error
+	var matched bool
+	for i := range r.routes {
+		rt := &r.routes[i]
+		if !rt.Match(e) {
+			continue
+		}
+		matched = true
+		atomic.AddUint64(&rt.count, 1)
+		if werr := rt.sink.WriteEntry(e); werr != nil && err == nil {
+			err = werr
+		}
+		if !rt.Continue {
+			return err
+		}
+	}
+	if matched {
+		return err
+	}
+	atomic.AddUint64(&r.defaultCount, 1)
+	if r.defaultSink != nil {
+		return r.defaultSink.WriteEntry(e)
+	}
+	return nil
+}
+
+// Counts returns the number of entries that have matched each route, by
+// route name.  Routes with the same name are counted together.
+func (r *Router) Counts() map[string]uint64 {
+	counts := make(map[string]uint64, len(r.routes)+1)
+	for i := range r.routes {
+		counts[r.routes[i].Name] += atomic.LoadUint64(&r.routes[i].count)
+	}
+	counts[DefaultRoute] += atomic.LoadUint64(&r.defaultCount)
+	return counts
+}
@@ -0,0 +1,54 @@
+package alog
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	auditBuf := new(bytes.Buffer)
+	tenantBuf := new(bytes.Buffer)
+	mainBuf := new(bytes.Buffer)
+	formatter := NewTextFormatter(" ", "")
+	router, err := NewRouter(map[string]Sink{
+		"audit":    NewSink(auditBuf, formatter),
+		"tenant-x": NewSink(tenantBuf, formatter),
+		"main":     NewSink(mainBuf, formatter),
+	}, []Route{
+		{Match: FieldEquals("component", "audit"), Sink: "audit"},
+		{Match: FieldEquals("tenant", "x"), Sink: "tenant-x", Continue: true},
+		{Name: "timeouts", Match: MessageMatches(regexp.MustCompile("timeout")), Sink: "main"},
+	}, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg := NewMulti([]SinkConfig{{Sink: router, Level: InfoLevel}})
+	lg.WithField("component", "audit").Info("user login")
+	lg.WithField("tenant", "x").Info("tenant request")
+	lg.Info("read timeout")
+	lg.Info("plain")
+	lg.Close()
+
+	if auditBuf.String() != " INFO user login (component=audit)\n" {
+		t.Errorf("wrong audit output: %q", auditBuf.String())
+	}
+	if tenantBuf.String() != " INFO tenant request (tenant=x)\n" {
+		t.Errorf("wrong tenant output: %q", tenantBuf.String())
+	}
+	// The tenant entry matched a route, so it does not go to the default sink.
+	expect := " INFO read timeout\n INFO plain\n"
+	if mainBuf.String() != expect {
+		t.Errorf("wrong main output: %q", mainBuf.String())
+	}
+
+	counts := router.Counts()
+	if counts["audit"] != 1 || counts["tenant-x"] != 1 || counts["timeouts"] != 1 || counts[DefaultRoute] != 1 {
+		t.Error("wrong counts:", counts)
+	}
+
+	if _, err = NewRouter(nil, []Route{{Match: LevelAtLeast(ErrorLevel), Sink: "none"}}, ""); err == nil {
+		t.Error("expected error for unknown sink")
+	}
+}
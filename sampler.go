@@ -0,0 +1,114 @@
+package alog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SampledOutField is the field that holds the number of entries that were
+// dropped by sampling, since the previous entry with the same level and
+// message was logged.
+const SampledOutField = "sampled_out"
+
+// maxSampleKeys is the number of distinct messages a sampler tracks before it
+// discards counters that have nothing to report.
+const maxSampleKeys = 4096
+
+type sampleKey struct {
+	level Level
+	msg   string
+}
+
+type sampleCounter struct {
+	start   time.Time
+	n       int
+	dropped int
+}
+
+type sampler struct {
+	tick       time.Duration
+	first      int
+	thereafter int
+	mutex      sync.Mutex
+	counters   map[sampleKey]*sampleCounter
+}
+
+// NewSampler creates a Logger that samples the entries logged to lg, to limit
+// the output of repetitive log calls.
+//
+// Entries are counted by level and message, where the message is the format
+// string of formatted log calls, or the formatted arguments of other calls.
+// In each tick interval, the first entries with the same level and message
+// are logged, then only every thereafter-th entry is logged.  If thereafter
+// is zero, no more entries are logged until the next interval.  The number of
+// entries dropped is reported in the SampledOutField of the next entry that
+// is logged with the same level and message.
+//
+// Sampling is done by the goroutine that logs an entry, before the entry is
+// queued, so dropped entries are never formatted or written.  Loggers created
+// from the returned Logger by WithFields share the same sampling counters.  If
+// lg is not created by this package, it is returned unchanged.
+func NewSampler(lg Logger, tick time.Duration, first, thereafter int) Logger {
+	s := &sampler{
+		tick:       tick,
+		first:      first,
+		thereafter: thereafter,
+		counters:   make(map[sampleKey]*sampleCounter),
+	}
+	return withGate(lg, s.sample)
+}
+
+func (s *sampler) sample(ent *entry) bool {
+	key := sampleKey{level: ent.level, msg: ent.format}
+	if key.msg == "" {
+		if len(ent.args) == 1 {
+			key.msg, _ = ent.args[0].(string)
+		}
+		if key.msg == "" {
+			key.msg = fmt.Sprint(ent.args...)
+		}
+	}
+
+	s.mutex.Lock()
+	c, ok := s.counters[key]
+	if !ok {
+		if len(s.counters) >= maxSampleKeys {
+			s.prune(ent.ts)
+		}
+		c = &sampleCounter{start: ent.ts}
+		s.counters[key] = c
+	} else if ent.ts.Sub(c.start) >= s.tick {
+		c.start = ent.ts
+		c.n = 0
+	}
+	c.n++
+	if c.n > s.first && (s.thereafter == 0 || (c.n-s.first)%s.thereafter != 0) {
+		c.dropped++
+		s.mutex.Unlock()
+		return false
+	}
+	dropped := c.dropped
+	c.dropped = 0
+	s.mutex.Unlock()
+
+	if dropped != 0 {
+		fields := make(Fields, len(ent.fields)+1)
+		for k, v := range ent.fields {
+			fields[k] = v
+		}
+		fields[SampledOutField] = dropped
+		ent.fields = fields
+	}
+	return true
+}
+
+// prune removes counters whose interval has expired and that have no dropped
+// entries to report.
+func (s *sampler) prune(now time.Time) {
+	for key, c := range s.counters {
+		if c.dropped == 0 && now.Sub(c.start) >= s.tick {
+			delete(s.counters, key)
+		}
+	}
+}
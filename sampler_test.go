@@ -0,0 +1,38 @@
+package alog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, DebugLevel, " ", "")
+	slg := NewSampler(lg, time.Minute, 2, 3)
+
+	for i := 0; i < 10; i++ {
+		slg.Debug("polling")
+		slg.WithField("i", i).Infof("item %d", i)
+	}
+	slg.Warn("different")
+	lg.Close()
+
+	// Entries 1, 2, 5, and 8 of each message are logged.
+	expect := []string{
+		" DEBUG polling",
+		" INFO item 0 (i=0)",
+		" DEBUG polling",
+		" INFO item 1 (i=1)",
+		" DEBUG polling (sampled_out=2)",
+		" INFO item 4 ",
+		" DEBUG polling (sampled_out=2)",
+		" INFO item 7 ",
+		" WARN different",
+	}
+	lines := checkLines(t, buf, expect...)
+	if !strings.Contains(lines[5], "(sampled_out=2)") {
+		t.Error("missing sampled count:", lines[5])
+	}
+}
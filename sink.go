@@ -0,0 +1,206 @@
+package alog
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrQueueFull is reported to the ErrorHandler when an entry is dropped
+// because a sink's queue is full.
+var ErrQueueFull = errors.New("sink queue is full, entry dropped")
+
+const defaultQueueSize = 64
+
+// Sink is a destination that log entries are written to.
+type Sink interface {
+	// WriteEntry writes a log entry.  It is only called by one goroutine at a
+	// time.  The Entry may be shared with other sinks, and must not be
+	// modified or retained after WriteEntry returns.
+	WriteEntry(e *Entry) error
+}
+
+type writerSink struct {
+	buf       []byte
+	out       io.Writer
+	formatter Formatter
+}
+
+// NewSink creates a Sink that formats each entry with formatter, and writes
+// it to out with a single call to out's Write method.  If out is nil, then
+// entries are written to os.Stdout.
+func NewSink(out io.Writer, formatter Formatter) Sink {
+	if out == nil {
+		out = os.Stdout
+	}
+	return &writerSink{
+		out:       out,
+		formatter: formatter,
+	}
+}
+
+func (s *writerSink) WriteEntry(e *Entry) error {
+	var err error
+	s.buf, err = s.formatter.Format(s.buf[:0], e)
+	if err != nil {
+		return err
+	}
+	_, err = s.out.Write(s.buf)
+	return err
+}
+
+// SinkConfig configures one of the sinks of a Logger created by NewMulti.
+type SinkConfig struct {
+	// Sink is where entries are written.
+	Sink Sink
+
+	// Level is the severity level to write to the sink at.  Set Level to
+	// NoLevel to write all entries to the sink, without a level.
+	Level Level
+
+	// QueueSize is the number of entries that can wait to be written to the
+	// sink.  If the queue is full, then entries for the sink are dropped and
+	// ErrQueueFull is reported to the Logger's ErrorHandler.  If zero, the
+	// default size of 64 is used.
+	QueueSize int
+}
+
+// NewMulti creates a new Logger instance that writes log entries to multiple
+// sinks.  Each sink has its own level and its own queue of entries, which is
+// written by a separate goroutine, so a slow sink does not delay writing to
+// the other sinks.
+//
+// The Logger's level is the most verbose level of any of its sinks, or
+// NoLevel if any sink's level is NoLevel.
+//
+// Any options are applied to configure additional Logger behavior.
+func NewMulti(sinks []SinkConfig, options ...Option) Logger {
+	var level Level
+	for i, sc := range sinks {
+		if i == 0 || sc.Level == NoLevel || (level != NoLevel && sc.Level > level) {
+			level = sc.Level
+		}
+	}
+	a := newLogger(level, options)
+	a.sinks = make([]*sinkQueue, len(sinks))
+	for i, sc := range sinks {
+		size := sc.QueueSize
+		if size <= 0 {
+			size = defaultQueueSize
+		}
+		a.sinks[i] = newSinkQueue(sc.Sink, sc.Level, size)
+	}
+	go a.run()
+	return a
+}
+
+// sinkQueue filters entries by level for a sink and, if the sink has a queue,
+// runs the goroutine that writes queued entries to the sink.
+type sinkQueue struct {
+	sink    Sink
+	level   Level
+	ch      chan *Entry
+	flushCh chan chan struct{}
+	done    chan struct{}
+	errFunc func(error)
+}
+
+// newSinkQueue creates a sinkQueue.  If size is zero, entries are written to
+// the sink by the goroutine that sends them.
+func newSinkQueue(sink Sink, level Level, size int) *sinkQueue {
+	sq := &sinkQueue{
+		sink:  sink,
+		level: clampLevel(level),
+	}
+	if size != 0 {
+		sq.ch = make(chan *Entry, size)
+		sq.flushCh = make(chan chan struct{})
+	}
+	return sq
+}
+
+func (sq *sinkQueue) start(errFunc func(error)) {
+	sq.errFunc = errFunc
+	if sq.ch == nil {
+		return
+	}
+	sq.done = make(chan struct{})
+	go func() {
+		defer close(sq.done)
+		for {
+			select {
+			case e, ok := <-sq.ch:
+				if !ok {
+					return
+				}
+				sq.write(e)
+			case flushed := <-sq.flushCh:
+				sq.drain()
+				close(flushed)
+			}
+		}
+	}()
+}
+
+// drain writes the entries that are in the queue.
+func (sq *sinkQueue) drain() {
+	for {
+		select {
+		case e := <-sq.ch:
+			sq.write(e)
+		default:
+			return
+		}
+	}
+}
+
+// send sends an entry to the sink, if the entry is at a level the sink
+// writes.  If the sink does not do leveled logging, it is sent a copy of the
+// entry that has no level.
+func (sq *sinkQueue) send(e *Entry) {
+	if sq.level == NoLevel {
+		if e.Level != NoLevel {
+			noLevel := *e
+			noLevel.Level = NoLevel
+			e = &noLevel
+		}
+	} else if e.Level > sq.level {
+		return
+	}
+	if sq.ch == nil {
+		sq.write(e)
+		return
+	}
+	select {
+	case sq.ch <- e:
+	default:
+		sq.errFunc(ErrQueueFull)
+	}
+}
+
+func (sq *sinkQueue) write(e *Entry) {
+	if err := sq.sink.WriteEntry(e); err != nil {
+		sq.errFunc(err)
+	}
+}
+
+// flush waits for all queued entries to be written to the sink.  It must be
+// called by the goroutine that sends entries, so that no entries are queued
+// while the queue is drained.
+func (sq *sinkQueue) flush() {
+	if sq.ch == nil {
+		return
+	}
+	flushed := make(chan struct{})
+	sq.flushCh <- flushed
+	<-flushed
+}
+
+// stop waits for all queued entries to be written to the sink.
+func (sq *sinkQueue) stop() {
+	if sq.ch == nil {
+		return
+	}
+	close(sq.ch)
+	<-sq.done
+}
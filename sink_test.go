@@ -0,0 +1,72 @@
+package alog
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingSink blocks writing its first entry until released.
+type blockingSink struct {
+	started chan struct{}
+	release chan struct{}
+	count   int32
+}
+
+func (s *blockingSink) WriteEntry(e *Entry) error {
+	if atomic.AddInt32(&s.count, 1) == 1 {
+		close(s.started)
+		<-s.release
+	}
+	return nil
+}
+
+func TestMulti(t *testing.T) {
+	jsonBuf := new(bytes.Buffer)
+	textBuf := new(bytes.Buffer)
+	slow := &blockingSink{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	var dropped int32
+	lg := NewMulti([]SinkConfig{
+		{Sink: NewSink(jsonBuf, NewJSONFormatter(" ")), Level: DebugLevel},
+		{Sink: NewSink(textBuf, NewTextFormatter(" ", "")), Level: WarnLevel},
+		{Sink: slow, Level: ErrorLevel, QueueSize: 1},
+	}, ErrorHandler(func(err error) {
+		if err == ErrQueueFull {
+			atomic.AddInt32(&dropped, 1)
+		}
+	}))
+
+	lg.Error("first error")
+	<-slow.started
+	lg.Debug("debug message")
+	lg.Warn("warn message")
+	lg.Error("second error")
+	lg.Error("third error")
+	// Wait for the third error to be dropped by the slow sink.
+	for i := 0; atomic.LoadInt32(&dropped) == 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(slow.release)
+	lg.Close()
+
+	jsonLines := strings.Split(strings.TrimSpace(jsonBuf.String()), "\n")
+	if len(jsonLines) != 5 {
+		t.Errorf("expected 5 JSON entries, got %d", len(jsonLines))
+	}
+	if !strings.Contains(jsonLines[1], `"level":"debug"`) {
+		t.Error("bad JSON entry:", jsonLines[1])
+	}
+	expect := " ERROR first error\n WARN warn message\n ERROR second error\n ERROR third error\n"
+	if textBuf.String() != expect {
+		t.Errorf("wrong text output: %q", textBuf.String())
+	}
+	if slow.count != 2 || dropped != 1 {
+		t.Errorf("expected 2 entries written to slow sink and 1 dropped, got %d and %d",
+			slow.count, dropped)
+	}
+}
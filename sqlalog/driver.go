@@ -0,0 +1,283 @@
+package sqlalog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+type wrappedDriver struct {
+	parent driver.Driver
+	cfg    *Config
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.parent.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{parent: c, cfg: d.cfg}, nil
+}
+
+func (d *wrappedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.parent.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &wrappedConnector{parent: c, cfg: d.cfg}, nil
+	}
+	return &dsnConnector{name: name, driver: d}, nil
+}
+
+// dsnConnector is the Connector of a driver that does not implement
+// driver.DriverContext.
+type dsnConnector struct {
+	name   string
+	driver *wrappedDriver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type wrappedConnector struct {
+	parent driver.Connector
+	cfg    *Config
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.parent.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{parent: conn, cfg: c.cfg}, nil
+}
+
+func (c *wrappedConnector) Driver() driver.Driver {
+	return &wrappedDriver{parent: c.parent.Driver(), cfg: c.cfg}
+}
+
+// wrappedConn implements the optional connection interfaces, and falls back
+// to the behavior that database/sql uses when the parent connection does not
+// implement them.
+type wrappedConn struct {
+	parent driver.Conn
+	cfg    *Config
+}
+
+func (c *wrappedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *wrappedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	var s driver.Stmt
+	var err error
+	if cp, ok := c.parent.(driver.ConnPrepareContext); ok {
+		s, err = cp.PrepareContext(ctx, query)
+	} else {
+		s, err = c.parent.Prepare(query)
+	}
+	if err != nil {
+		c.cfg.log(ctx, "prepare", query, nil, -1, start, err)
+		return nil, err
+	}
+	return &wrappedStmt{parent: s, conn: c, query: query}, nil
+}
+
+func (c *wrappedConn) Close() error {
+	return c.parent.Close()
+}
+
+func (c *wrappedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var tx driver.Tx
+	var err error
+	if cb, ok := c.parent.(driver.ConnBeginTx); ok {
+		tx, err = cb.BeginTx(ctx, opts)
+	} else if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		err = errors.New("sqlalog: driver does not support non-default transaction options")
+	} else {
+		tx, err = c.parent.Begin()
+	}
+	c.cfg.log(ctx, "begin", "", nil, -1, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedTx{parent: tx, ctx: ctx, cfg: c.cfg}, nil
+}
+
+func (c *wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if ec, ok := c.parent.(driver.ExecerContext); ok {
+		res, err = ec.ExecContext(ctx, query, args)
+	} else if e, ok := c.parent.(driver.Execer); ok {
+		var vals []driver.Value
+		if vals, err = values(args); err == nil {
+			res, err = e.Exec(query, vals)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	rows := int64(-1)
+	if err == nil {
+		rows = rowsAffected(res)
+	}
+	c.cfg.log(ctx, "exec", query, args, rows, start, err)
+	return res, err
+}
+
+func (c *wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if qc, ok := c.parent.(driver.QueryerContext); ok {
+		rows, err = qc.QueryContext(ctx, query, args)
+	} else if q, ok := c.parent.(driver.Queryer); ok {
+		var vals []driver.Value
+		if vals, err = values(args); err == nil {
+			rows, err = q.Query(query, vals)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	c.cfg.log(ctx, "query", query, args, -1, start, err)
+	return rows, err
+}
+
+func (c *wrappedConn) Ping(ctx context.Context) error {
+	if p, ok := c.parent.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.parent.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) IsValid() bool {
+	if v, ok := c.parent.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *wrappedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.parent.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type wrappedStmt struct {
+	parent driver.Stmt
+	conn   *wrappedConn
+	query  string
+}
+
+func (s *wrappedStmt) Close() error {
+	return s.parent.Close()
+}
+
+func (s *wrappedStmt) NumInput() int {
+	return s.parent.NumInput()
+}
+
+func (s *wrappedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *wrappedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if se, ok := s.parent.(driver.StmtExecContext); ok {
+		res, err = se.ExecContext(ctx, args)
+	} else {
+		var vals []driver.Value
+		if vals, err = values(args); err == nil {
+			res, err = s.parent.Exec(vals)
+		}
+	}
+	rows := int64(-1)
+	if err == nil {
+		rows = rowsAffected(res)
+	}
+	s.conn.cfg.log(ctx, "exec", s.query, args, rows, start, err)
+	return res, err
+}
+
+func (s *wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if sq, ok := s.parent.(driver.StmtQueryContext); ok {
+		rows, err = sq.QueryContext(ctx, args)
+	} else {
+		var vals []driver.Value
+		if vals, err = values(args); err == nil {
+			rows, err = s.parent.Query(vals)
+		}
+	}
+	s.conn.cfg.log(ctx, "query", s.query, args, -1, start, err)
+	return rows, err
+}
+
+// CheckNamedValue uses the checker of the parent statement, or else of the
+// parent connection, as database/sql would without the wrapper.  If neither
+// checks the value, database/sql uses ColumnConverter.
+func (s *wrappedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.parent.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// ColumnConverter returns the converter of the parent statement, or the
+// default converter that database/sql would use without the wrapper.
+func (s *wrappedStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.parent.(driver.ColumnConverter); ok {
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+type wrappedTx struct {
+	parent driver.Tx
+	ctx    context.Context
+	cfg    *Config
+}
+
+func (tx *wrappedTx) Commit() error {
+	start := time.Now()
+	err := tx.parent.Commit()
+	tx.cfg.log(tx.ctx, "commit", "", nil, -1, start, err)
+	return err
+}
+
+func (tx *wrappedTx) Rollback() error {
+	start := time.Now()
+	err := tx.parent.Rollback()
+	tx.cfg.log(tx.ctx, "rollback", "", nil, -1, start, err)
+	return err
+}
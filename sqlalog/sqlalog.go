@@ -0,0 +1,191 @@
+/*
+Package sqlalog wraps a database/sql/driver.Driver to log the statements that
+are run through it.
+
+Each query, exec, and transaction operation is logged with the QueryField,
+ArgsField, DurationField, and, for execs, RowsAffectedField fields.  Failed
+operations are logged at ErrorLevel with the error, and operations that take
+at least the slow threshold are logged at WarnLevel with SlowField set.
+
+	sql.Register("postgres-logged", sqlalog.Wrap(&pq.Driver{}, sqlalog.Config{
+		Logger:        logger.Named("sql"),
+		SlowThreshold: 500 * time.Millisecond,
+		Redact:        sqlalog.RedactAll,
+	}))
+	db, err := sql.Open("postgres-logged", dsn)
+
+If the context given to a statement carries a Logger, as stored by
+alog.NewContext, then that Logger is used instead of Config.Logger, so that
+statements are logged with the fields of the request that ran them.
+*/
+package sqlalog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/gammazero/alog"
+)
+
+// Fields of the entries logged for statements.
+const (
+	QueryField        = "query"
+	ArgsField         = "args"
+	RowsAffectedField = "rows_affected"
+	DurationField     = "duration"
+	SlowField         = "slow"
+)
+
+// Redacted is the value that RedactAll and RedactNames log in place of an
+// argument.
+const Redacted = "[REDACTED]"
+
+// Config configures the logging of a wrapped driver.
+type Config struct {
+	// Logger is the Logger that statements are logged to, when the
+	// statement's context does not carry a Logger.  If nil, only statements
+	// with such a context are logged.
+	Logger alog.Logger
+
+	// Level is the level of successful statements.  If NoLevel, DebugLevel
+	// is used.
+	Level alog.Level
+
+	// SlowThreshold is the duration at or above which a statement is logged
+	// at WarnLevel.  If zero, statements are not logged as slow.
+	SlowThreshold time.Duration
+
+	// Redact returns the value to log for the argument with the given ordinal
+	// position, starting at 1, and name, which is empty for positional
+	// arguments.  If nil, arguments are logged as they are.
+	Redact func(ordinal int, name string, value interface{}) interface{}
+
+	// OmitArgs disables logging of arguments.
+	OmitArgs bool
+}
+
+// RedactAll is a Config.Redact function that redacts every argument.
+func RedactAll(ordinal int, name string, value interface{}) interface{} {
+	return Redacted
+}
+
+// RedactNames returns a Config.Redact function that redacts the named
+// arguments with any of the given names.
+func RedactNames(names ...string) func(int, string, interface{}) interface{} {
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+	}
+	return func(ordinal int, name string, value interface{}) interface{} {
+		if _, ok := set[name]; ok {
+			return Redacted
+		}
+		return value
+	}
+}
+
+// Wrap returns a driver.Driver that logs the statements run by the
+// connections that d opens.
+func Wrap(d driver.Driver, cfg Config) driver.Driver {
+	if cfg.Level == alog.NoLevel {
+		cfg.Level = alog.DebugLevel
+	}
+	return &wrappedDriver{parent: d, cfg: &cfg}
+}
+
+// WrapConnector returns a driver.Connector, for use with sql.OpenDB, that
+// logs the statements run by the connections that c opens.
+func WrapConnector(c driver.Connector, cfg Config) driver.Connector {
+	if cfg.Level == alog.NoLevel {
+		cfg.Level = alog.DebugLevel
+	}
+	return &wrappedConnector{parent: c, cfg: &cfg}
+}
+
+// log logs an operation that started at start.  An empty query is not logged,
+// and rows is not logged if it is negative.
+func (cfg *Config) log(ctx context.Context, msg, query string, args []driver.NamedValue, rows int64, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	lg := alog.FromContext(ctx)
+	if lg == nil {
+		if lg = cfg.Logger; lg == nil {
+			return
+		}
+	}
+	dur := time.Since(start)
+	level := cfg.Level
+	slow := cfg.SlowThreshold > 0 && dur >= cfg.SlowThreshold
+	if err != nil {
+		level = alog.ErrorLevel
+	} else if slow && level > alog.WarnLevel {
+		level = alog.WarnLevel
+	}
+	if !lg.Enabled(level) {
+		return
+	}
+
+	fields := alog.Fields{DurationField: dur}
+	if query != "" {
+		fields[QueryField] = query
+	}
+	if len(args) != 0 && !cfg.OmitArgs {
+		fields[ArgsField] = cfg.redact(args)
+	}
+	if rows >= 0 {
+		fields[RowsAffectedField] = rows
+	}
+	if slow {
+		fields[SlowField] = true
+	}
+	if err != nil {
+		fields[alog.ErrorField] = err
+	}
+	lg.WithFields(fields).Log(level, msg)
+}
+
+func (cfg *Config) redact(args []driver.NamedValue) []interface{} {
+	vals := make([]interface{}, len(args))
+	for i, arg := range args {
+		if cfg.Redact != nil {
+			vals[i] = cfg.Redact(arg.Ordinal, arg.Name, arg.Value)
+		} else {
+			vals[i] = arg.Value
+		}
+	}
+	return vals
+}
+
+// namedValues converts positional arguments, as given to the deprecated
+// driver methods without a context.
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// values converts arguments for the deprecated driver methods, which do not
+// support named arguments.
+func values(args []driver.NamedValue) ([]driver.Value, error) {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sqlalog: driver does not support named arguments")
+		}
+		vals[i] = arg.Value
+	}
+	return vals, nil
+}
+
+func rowsAffected(res driver.Result) int64 {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
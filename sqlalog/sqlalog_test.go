@@ -0,0 +1,271 @@
+package sqlalog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gammazero/alog"
+)
+
+// fakeDriver opens connections that implement the context-aware execer and
+// queryer interfaces.  A query containing "fail" returns an error, and one
+// containing "slow" takes slowDelay.
+type fakeDriver struct{}
+
+const slowDelay = 30 * time.Millisecond
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	switch {
+	case strings.Contains(query, "fail"):
+		return nil, errors.New("syntax error")
+	case strings.Contains(query, "convert"):
+		return &convertStmt{fakeStmt{query: query}}, nil
+	case strings.Contains(query, "check"):
+		return &checkStmt{fakeStmt{query: query}}, nil
+	}
+	return &fakeStmt{query: query}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return run(query, driver.RowsAffected(3))
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if _, err := run(query, nil); err != nil {
+		return nil, err
+	}
+	return &fakeRows{}, nil
+}
+
+func run(query string, res driver.Result) (driver.Result, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("table not found")
+	}
+	if strings.Contains(query, "slow") {
+		time.Sleep(slowDelay)
+	}
+	return res, nil
+}
+
+type fakeStmt struct {
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return run(s.query, driver.RowsAffected(1))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+// convertStmt converts string arguments to upper case with a ColumnConverter.
+type convertStmt struct {
+	fakeStmt
+}
+
+func (s *convertStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return upperConverter{}
+}
+
+type upperConverter struct{}
+
+func (upperConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if s, ok := v.(string); ok {
+		return strings.ToUpper(s), nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+// checkStmt converts bool arguments to "yes" or "no" with a
+// NamedValueChecker.
+type checkStmt struct {
+	fakeStmt
+}
+
+func (s *checkStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if b, ok := nv.Value.(bool); ok {
+		nv.Value = map[bool]string{true: "yes", false: "no"}[b]
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// fakeRows has one column and one row.
+type fakeRows struct {
+	done bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(42)
+	return nil
+}
+
+func openDB(t *testing.T, cfg Config) *sql.DB {
+	t.Helper()
+	c, err := Wrap(fakeDriver{}, cfg).(driver.DriverContext).OpenConnector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLogging(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := alog.NewJSON(buf, alog.DebugLevel, "")
+	db := openDB(t, Config{
+		Logger:        lg,
+		SlowThreshold: slowDelay / 2,
+		Redact:        RedactNames("password"),
+	})
+
+	if _, err := db.Exec("insert users", "rick", sql.Named("password", "wubba")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("fail"); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := db.Exec("slow update"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow("select n", 7).Scan(&n); err != nil || n != 42 {
+		t.Fatal("wrong query result:", n, err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare("delete users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stmt.Exec(9); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	lg.Close()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("expected 7 lines, got %d: %q", len(lines), lines)
+	}
+	for i, wants := range [][]string{
+		{`"level":"debug"`, `"msg":"exec"`, `"query":"insert users"`,
+			`"args":["rick","[REDACTED]"]`, `"rows_affected":3`, `"duration":`},
+		{`"level":"error"`, `"msg":"exec"`, `"query":"fail"`, `"error":"table not found"`},
+		{`"level":"warn"`, `"query":"slow update"`, `"slow":true`},
+		{`"level":"debug"`, `"msg":"query"`, `"query":"select n"`, `"args":[7]`},
+		{`"msg":"begin"`},
+		{`"msg":"commit"`},
+		{`"msg":"exec"`, `"query":"delete users"`, `"args":[9]`, `"rows_affected":1`},
+	} {
+		for _, want := range wants {
+			if !strings.Contains(lines[i], want) {
+				t.Errorf("line %d: missing %s in %s", i, want, lines[i])
+			}
+		}
+	}
+	if strings.Contains(buf.String(), "wubba") {
+		t.Error("password was not redacted")
+	}
+	if strings.Contains(lines[0], `"slow"`) || strings.Contains(lines[4], `"query"`) {
+		t.Error("unexpected fields")
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := alog.NewJSON(buf, alog.InfoLevel, "")
+	reqBuf := new(bytes.Buffer)
+	reqLogger := alog.NewJSON(reqBuf, alog.DebugLevel, "")
+	db := openDB(t, Config{Logger: lg, Level: alog.InfoLevel, Redact: RedactAll})
+
+	ctx := alog.NewContext(context.Background(), reqLogger.WithField("request_id", "r-1"))
+	if _, err := db.ExecContext(ctx, "insert", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Prepare("fail"); err == nil {
+		t.Fatal("expected error")
+	}
+	lg.Close()
+	reqLogger.Close()
+
+	for _, want := range []string{`"level":"info"`, `"request_id":"r-1"`, `"args":["[REDACTED]"]`} {
+		if !strings.Contains(reqBuf.String(), want) {
+			t.Errorf("missing %s in %s", want, reqBuf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), `"msg":"prepare"`) || !strings.Contains(buf.String(), `"error":"syntax error"`) {
+		t.Error("prepare error not logged:", buf.String())
+	}
+	if strings.Contains(buf.String(), "insert") {
+		t.Error("statement with context logger logged to Config.Logger")
+	}
+}
+
+func TestStmtConversion(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := alog.NewJSON(buf, alog.DebugLevel, "")
+	db := openDB(t, Config{Logger: lg})
+
+	for _, tc := range []struct {
+		query string
+		arg   interface{}
+	}{
+		{"convert", "abc"},
+		{"check", true},
+	} {
+		stmt, err := db.Prepare(tc.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = stmt.Exec(tc.arg); err != nil {
+			t.Fatal(err)
+		}
+		stmt.Close()
+	}
+	lg.Close()
+
+	for _, want := range []string{`"args":["ABC"]`, `"args":["yes"]`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %s in %s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package alog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Facility is a syslog facility code, which identifies the type of program
+// logging a message.
+type Facility int
+
+// Syslog facilities
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLpr
+	FacilityNews
+	FacilityUucp
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	_ // ntp
+	_ // security
+	_ // console
+	_ // solaris-cron
+	FacilityLocal0
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// SyslogFormat selects the syslog message format.
+type SyslogFormat int
+
+const (
+	// RFC5424 is the current syslog protocol format.  Fields are sent as
+	// structured data.
+	RFC5424 SyslogFormat = iota
+	// RFC3164 is the legacy BSD syslog format.  Fields are appended to the
+	// message in the same form as text log output.
+	RFC3164
+)
+
+// syslogSDID is the SD-ID of the structured data element that holds an
+// entry's fields.  The enterprise number 32473 is reserved for documentation
+// and examples by RFC 5612.
+const syslogSDID = "fields@32473"
+
+// syslog severities
+const (
+	sevEmerg = iota
+	sevAlert
+	sevCrit
+	sevErr
+	sevWarning
+	sevNotice
+	sevInfo
+	sevDebug
+)
+
+// SyslogSeverity maps a Level to a syslog severity, from 0 (emerg) to 7
+// (debug).  PanicLevel is emerg, FatalLevel is crit, ErrorLevel is err,
+// WarnLevel is warning, InfoLevel and NoLevel are info, and DebugLevel and
+// TraceLevel are debug.  Custom levels are mapped to the severity
+// of the next less severe level, except that levels between PanicLevel and
+// FatalLevel are alert, and levels between WarnLevel and InfoLevel are notice.
+func SyslogSeverity(level Level) int {
+	switch level = legacyLevel(level); {
+	case level == NoLevel:
+		return sevInfo
+	case level <= PanicLevel:
+		return sevEmerg
+	case level < FatalLevel:
+		return sevAlert
+	case level == FatalLevel:
+		return sevCrit
+	case level <= ErrorLevel:
+		return sevErr
+	case level <= WarnLevel:
+		return sevWarning
+	case level < InfoLevel:
+		return sevNotice
+	case level == InfoLevel:
+		return sevInfo
+	}
+	return sevDebug
+}
+
+type syslogFormatter struct {
+	format   SyslogFormat
+	facility Facility
+	hostname string
+	appName  string
+	procID   string
+}
+
+// NewSyslogFormatter creates a Formatter that formats entries as syslog
+// messages, in either RFC 5424 or RFC 3164 format.  Each entry's Level is
+// mapped to a syslog severity: PanicLevel to emerg, FatalLevel to crit,
+// ErrorLevel to err, WarnLevel to warning, InfoLevel and NoLevel to info, and
+// DebugLevel and TraceLevel to debug.
+//
+// The appName identifies the program in each message.  If empty, the name of
+// the program's executable is used.
+//
+// Each formatted message is terminated by a newline, which SyslogWriter
+// removes before sending the message.
+func NewSyslogFormatter(format SyslogFormat, facility Facility, appName string) Formatter {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	if appName == "" {
+		if exe, err := os.Executable(); err == nil {
+			appName = filepath.Base(exe)
+		} else {
+			appName = "-"
+		}
+	}
+	return &syslogFormatter{
+		format:   format,
+		facility: facility,
+		hostname: hostname,
+		appName:  appName,
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+}
+
+func (f *syslogFormatter) Format(buf []byte, e *Entry) ([]byte, error) {
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(f.facility)*8+int64(SyslogSeverity(e.Level)), 10)
+	buf = append(buf, '>')
+
+	if f.format == RFC3164 {
+		buf = e.Time.AppendFormat(buf, time.Stamp)
+		buf = append(buf, ' ')
+		buf = append(buf, f.hostname...)
+		buf = append(buf, ' ')
+		buf = append(buf, f.appName...)
+		buf = append(buf, '[')
+		buf = append(buf, f.procID...)
+		buf = append(buf, "]: "...)
+		buf = append(buf, e.Message...)
+		for k, v := range e.Fields {
+			buf = append(buf, " ("...)
+			buf = append(buf, k...)
+			buf = append(buf, '=')
+			buf = append(buf, fmt.Sprint(v)...)
+			buf = append(buf, ')')
+		}
+		return append(buf, '\n'), nil
+	}
+
+	// VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID
+	buf = append(buf, "1 "...)
+	buf = e.Time.AppendFormat(buf, "2006-01-02T15:04:05.000000Z07:00")
+	buf = append(buf, ' ')
+	buf = append(buf, f.hostname...)
+	buf = append(buf, ' ')
+	buf = append(buf, f.appName...)
+	buf = append(buf, ' ')
+	buf = append(buf, f.procID...)
+	buf = append(buf, " - "...)
+	buf = appendStructuredData(buf, e.Fields)
+	if e.Message != "" {
+		buf = append(buf, ' ')
+		buf = append(buf, e.Message...)
+	}
+	return append(buf, '\n'), nil
+}
+
+// appendStructuredData appends the fields as an RFC 5424 structured data
+// element, or the nil value "-" if there are no fields.
+func appendStructuredData(buf []byte, fields Fields) []byte {
+	if len(fields) == 0 {
+		return append(buf, '-')
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf = append(buf, '[')
+	buf = append(buf, syslogSDID...)
+	for _, k := range keys {
+		buf = append(buf, ' ')
+		buf = appendSDName(buf, k)
+		buf = append(buf, `="`...)
+		buf = appendSDValue(buf, fmt.Sprint(fields[k]))
+		buf = append(buf, '"')
+	}
+	return append(buf, ']')
+}
+
+// appendSDName appends a PARAM-NAME, which is 1 to 32 printable ASCII
+// characters other than '=', ' ', ']', and '"'.  Other characters are
+// replaced by '_'.
+func appendSDName(buf []byte, name string) []byte {
+	if name == "" {
+		return append(buf, '_')
+	}
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+// appendSDValue appends a PARAM-VALUE, escaping '"', '\', and ']'.
+func appendSDValue(buf []byte, value string) []byte {
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\\', ']':
+			buf = append(buf, '\\', c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// SyslogWriter is an io.Writer that sends each write as a syslog message to a
+// syslog server.  It is used as the output of a Logger that formats entries
+// with a syslog Formatter.
+type SyslogWriter struct {
+	network string
+	raddr   string
+	framed  bool
+	mutex   sync.Mutex
+	conn    net.Conn
+}
+
+// DialSyslog connects to a syslog server at raddr.
+//
+// Network is one of "udp", "udp4", "udp6", or "unixgram", for which each
+// message is sent as a datagram, or "tcp", "tcp4", "tcp6", or "unix", for
+// which each message is sent using the octet-counting framing of RFC 6587.
+func DialSyslog(network, raddr string) (*SyslogWriter, error) {
+	var framed bool
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		framed = true
+	case "udp", "udp4", "udp6", "unixgram":
+	default:
+		return nil, errors.New("unsupported syslog network: " + network)
+	}
+	w := &SyslogWriter{
+		network: network,
+		raddr:   raddr,
+		framed:  framed,
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write sends p as a single syslog message, removing any trailing newline.
+// If sending fails before any of the message is sent, the connection is
+// reestablished and the message is sent again once.  If part of the message
+// was sent, it is not sent again, since the server would receive it twice or
+// receive a broken frame, and the connection is reestablished by the next
+// Write.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	msg := bytes.TrimSuffix(p, []byte{'\n'})
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.conn != nil {
+		n, err := w.send(msg)
+		if err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+		if n != 0 {
+			return 0, err
+		}
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.send(msg); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the syslog server.
+func (w *SyslogWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *SyslogWriter) connect() error {
+	conn, err := net.Dial(w.network, w.raddr)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// send writes the message to the connection, and returns the number of bytes
+// written.
+func (w *SyslogWriter) send(msg []byte) (int, error) {
+	if !w.framed {
+		return w.conn.Write(msg)
+	}
+	frame := make([]byte, 0, len(msg)+8)
+	frame = strconv.AppendInt(frame, int64(len(msg)), 10)
+	frame = append(frame, ' ')
+	frame = append(frame, msg...)
+	return w.conn.Write(frame)
+}
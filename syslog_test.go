@@ -0,0 +1,186 @@
+package alog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w, err := DialSyslog("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	lg := New(w, DebugLevel, NewSyslogFormatter(RFC5424, FacilityLocal0, "testapp"))
+	lg.WithFields(Fields{"user": "bob", "q": `say "hi"]`}).Error("login failed")
+	lg.Close()
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// local0 (16) * 8 + err (3) = 131
+	if !strings.HasPrefix(msg, "<131>1 ") {
+		t.Error("bad priority or version:", msg)
+	}
+	if !strings.Contains(msg, " testapp ") {
+		t.Error("missing app name:", msg)
+	}
+	if !strings.HasSuffix(msg, ` - [fields@32473 q="say \"hi\"\]" user="bob"] login failed`) {
+		t.Error("bad structured data or message:", msg)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	msgCh := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(size))
+			msg := make([]byte, n)
+			if _, err = io.ReadFull(r, msg); err != nil {
+				return
+			}
+			msgCh <- string(msg)
+		}
+	}()
+
+	w, err := DialSyslog("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	lg := New(w, InfoLevel, NewSyslogFormatter(RFC3164, FacilityDaemon, "testapp"))
+	lg.Warn("disk low")
+	lg.Print("no level")
+	lg.Close()
+
+	for _, expect := range []string{"<28>", "<30>"} {
+		select {
+		case msg := <-msgCh:
+			if !strings.HasPrefix(msg, expect) {
+				t.Errorf("expected priority %s: %s", expect, msg)
+			}
+			if !strings.Contains(msg, " testapp[") {
+				t.Error("missing tag:", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for message")
+		}
+	}
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix datagram sockets not supported")
+	}
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w, err := DialSyslog("unixgram", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	lg := New(w, DebugLevel, NewSyslogFormatter(RFC5424, FacilityUser, "testapp"))
+	lg.Debug("local message")
+	lg.Close()
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<15>1 ") || !strings.HasSuffix(msg, " - - local message") {
+		t.Error("bad message:", msg)
+	}
+}
+
+// partialConn writes only part of each message before failing.
+type partialConn struct {
+	net.Conn
+	writes int
+}
+
+func (c *partialConn) Write(p []byte) (int, error) {
+	c.writes++
+	return len(p) / 2, errors.New("connection reset")
+}
+
+func (c *partialConn) Close() error { return nil }
+
+func TestSyslogPartialWrite(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+
+	w, err := DialSyslog("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.conn.Close()
+	partial := &partialConn{}
+	w.conn = partial
+
+	// A message that was partly sent is not sent again.
+	if _, err = w.Write([]byte("<30>partly sent\n")); err == nil {
+		t.Fatal("expected error")
+	}
+	if partial.writes != 1 || w.conn != nil {
+		t.Fatal("message with partial write was sent again")
+	}
+	// The next message reconnects.
+	if _, err = w.Write([]byte("<30>next\n")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,164 @@
+package alog
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// verbosity is the global verbosity set by SetVerbosity.
+var verbosity int32
+
+// vmodule holds the *vmoduleState set by SetVModule.
+var vmodule atomic.Value
+
+// noVModule is the cached verbosity of a callsite in a file that no vmodule
+// pattern matches.  Such callsites use the global verbosity, which V checks
+// first.
+const noVModule = math.MinInt32
+
+// vmoduleCount is the number of vmodule patterns, so that V can skip looking
+// at them when there are none.
+var vmoduleCount int32
+
+type vmodulePattern struct {
+	pattern string
+	// full is true if the pattern contains "/", and so is matched against
+	// the trailing components of the file's path, instead of its base name.
+	full  bool
+	level int32
+}
+
+type vmoduleState struct {
+	spec     string
+	patterns []vmodulePattern
+	// sites caches the verbosity of each callsite of V, by program counter.
+	sites sync.Map
+}
+
+// SetVerbosity sets the global verbosity that Logger.V compares against.
+func SetVerbosity(v int) {
+	atomic.StoreInt32(&verbosity, int32(v))
+}
+
+// Verbosity returns the global verbosity.
+func Verbosity() int {
+	return int(atomic.LoadInt32(&verbosity))
+}
+
+// SetVModule sets per-file verbosities that override the global verbosity,
+// in the manner of glog's -vmodule flag.  The spec is a comma-separated list
+// of pattern=N settings, such as "gopher*=3,net/*=2".  A pattern without "/"
+// is matched against the base name of the calling source file, without the
+// ".go" extension.  A pattern with "/" is matched against the same number of
+// trailing path components.  Patterns use the syntax of path.Match, and the
+// first pattern that matches is used.  An empty spec removes all patterns.
+func SetVModule(spec string) error {
+	state := &vmoduleState{spec: spec}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		eq := strings.LastIndexByte(item, '=')
+		if eq == -1 {
+			return fmt.Errorf("vmodule: missing verbosity in %q", item)
+		}
+		pattern := strings.TrimSuffix(strings.TrimSpace(item[:eq]), ".go")
+		if _, err := path.Match(pattern, ""); pattern == "" || err != nil {
+			return fmt.Errorf("vmodule: invalid pattern %q", pattern)
+		}
+		level, err := strconv.ParseInt(strings.TrimSpace(item[eq+1:]), 10, 32)
+		if err != nil {
+			return fmt.Errorf("vmodule: invalid verbosity in %q", item)
+		}
+		state.patterns = append(state.patterns, vmodulePattern{
+			pattern: pattern,
+			full:    strings.Contains(pattern, "/"),
+			level:   int32(level),
+		})
+	}
+	vmodule.Store(state)
+	atomic.StoreInt32(&vmoduleCount, int32(len(state.patterns)))
+	return nil
+}
+
+// VModule returns the spec last given to SetVModule.
+func VModule() string {
+	state, _ := vmodule.Load().(*vmoduleState)
+	if state == nil {
+		return ""
+	}
+	return state.spec
+}
+
+// vEnabled reports whether V-logging at the given level is enabled for the
+// caller of V.  It must be called directly by V.
+func vEnabled(level int) bool {
+	if int32(level) <= atomic.LoadInt32(&verbosity) {
+		return true
+	}
+	if atomic.LoadInt32(&vmoduleCount) == 0 {
+		return false
+	}
+	state := vmodule.Load().(*vmoduleState)
+	var pcs [1]uintptr
+	// Skip runtime.Callers, vEnabled, and V.
+	if runtime.Callers(3, pcs[:]) == 0 {
+		return false
+	}
+	v, ok := state.sites.Load(pcs[0])
+	if !ok {
+		frame, _ := runtime.CallersFrames(pcs[:]).Next()
+		v, _ = state.sites.LoadOrStore(pcs[0], state.fileLevel(frame.File))
+	}
+	return int32(level) <= v.(int32)
+}
+
+// fileLevel returns the verbosity for the source file from the first
+// matching pattern, or noVModule if no pattern matches.
+func (state *vmoduleState) fileLevel(file string) int32 {
+	file = strings.TrimSuffix(file, ".go")
+	base := path.Base(file)
+	for _, p := range state.patterns {
+		name := base
+		if p.full {
+			name = trailingComponents(file, strings.Count(p.pattern, "/")+1)
+		}
+		if ok, _ := path.Match(p.pattern, name); ok {
+			return p.level
+		}
+	}
+	return noVModule
+}
+
+// trailingComponents returns the last n slash-separated components of file.
+func trailingComponents(file string, n int) string {
+	i := len(file)
+	for ; n > 0; n-- {
+		i = strings.LastIndexByte(file[:i], '/')
+		if i == -1 {
+			return file
+		}
+	}
+	return file[i+1:]
+}
+
+func (a *logger) V(level int) Logger {
+	if vEnabled(level) {
+		return a
+	}
+	return a.off
+}
+
+func (f *fieldLogger) V(level int) Logger {
+	if vEnabled(level) {
+		return f
+	}
+	return f.logger.off
+}
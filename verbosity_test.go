@@ -0,0 +1,66 @@
+package alog
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestV(t *testing.T) {
+	defer SetVerbosity(0)
+	defer SetVModule("")
+
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+	flg := lg.WithField("k", "v")
+
+	SetVerbosity(1)
+	lg.V(1).Info("v1")
+	lg.V(2).Info("v2 hidden")
+	flg.V(1).Info("v1 fields")
+	if lg.V(2).Enabled(InfoLevel) {
+		t.Error("V(2) should not be enabled")
+	}
+	lg.V(2).WithField("a", 1).Named("n").Error("hidden")
+
+	if err := SetVModule("verbosity_te*=3, other/*=9"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		lg.V(3).Info("v3 by vmodule")
+		lg.V(4).Info("v4 hidden")
+	}
+	if err := SetVModule("*/verbosity_test=4"); err != nil {
+		t.Fatal(err)
+	}
+	lg.V(4).Info("v4 by vmodule path")
+	if err := SetVModule("other=4"); err != nil {
+		t.Fatal(err)
+	}
+	lg.V(4).Info("v4 hidden")
+	lg.Close()
+
+	expect := []string{
+		" INFO v1",
+		" INFO v1 fields (k=v)",
+		" INFO v3 by vmodule",
+		" INFO v3 by vmodule",
+		" INFO v4 by vmodule path",
+	}
+	checkLines(t, buf, expect...)
+
+	for _, spec := range []string{"foo", "foo=x", "[=1"} {
+		if err := SetVModule(spec); err == nil {
+			t.Errorf("expected error setting vmodule %q", spec)
+		}
+	}
+}
+
+func BenchmarkVDisabled(b *testing.B) {
+	lg := NewText(io.Discard, InfoLevel, "", "")
+	defer lg.Close()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lg.V(2).Info("hidden")
+	}
+}
@@ -0,0 +1,228 @@
+package alog
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+)
+
+// maxLineSize is the size of the longest line that a Writer logs as one
+// entry.  A longer line is logged as several entries, so that a writer that
+// never writes a newline does not grow the buffer without bound.
+const maxLineSize = 64 * 1024
+
+// lineBuffer splits the data written to an io.Writer into lines.  Data
+// following the last newline is kept until the line is completed by a
+// subsequent write, or until it reaches maxLineSize.
+type lineBuffer struct {
+	buf   []byte
+	start int
+	lines [][]byte
+}
+
+// add appends p to the buffer and returns the lines that it completes,
+// without their line endings.  The lines are valid until the next call to add
+// or rest.
+func (b *lineBuffer) add(p []byte) [][]byte {
+	// Move any incomplete line to the start of the buffer.
+	b.buf = b.buf[:copy(b.buf, b.buf[b.start:])]
+	b.start = 0
+	b.buf = append(b.buf, p...)
+	b.lines = b.lines[:0]
+	for {
+		i := bytes.IndexByte(b.buf[b.start:], '\n')
+		if i < 0 {
+			if len(b.buf)-b.start < maxLineSize {
+				break
+			}
+			b.lines = append(b.lines, b.buf[b.start:b.start+maxLineSize])
+			b.start += maxLineSize
+			continue
+		}
+		b.lines = append(b.lines, bytes.TrimSuffix(b.buf[b.start:b.start+i], []byte{'\r'}))
+		b.start += i + 1
+	}
+	return b.lines
+}
+
+// rest removes and returns the data that does not end with a newline, which
+// is valid until the next call to add.
+func (b *lineBuffer) rest() []byte {
+	rest := b.buf[b.start:]
+	b.start = len(b.buf)
+	return rest
+}
+
+// logWriter is an io.Writer that splits the data written to it into lines,
+// and logs each line as a separate entry at a fixed level.  Lines are logged
+// through the fieldLogger, so that they have its fields, name level, gate,
+// and dynamic debug setting.
+type logWriter struct {
+	f      *fieldLogger
+	level  Level
+	header *stdLogHeader
+	mutex  sync.Mutex
+	lines  lineBuffer
+}
+
+// WriterOption configures the io.Writer returned by a Logger's Writer method.
+type WriterOption func(*logWriter)
+
+// StripStdLogHeader returns a WriterOption that removes the header that a
+// stdlib log.Logger with the given prefix and flags writes at the start of
+// each line, such as a log.Logger created by:
+//
+//	log.New(lg.Writer(alog.InfoLevel, alog.StripStdLogHeader("lib: ", log.LstdFlags)), "lib: ", log.LstdFlags)
+//
+// The header's timestamp and file name are removed, since lg provides its
+// own.  A line that does not start with the header is logged unchanged.
+func StripStdLogHeader(prefix string, flags int) WriterOption {
+	return func(w *logWriter) {
+		w.header = &stdLogHeader{prefix: []byte(prefix), flags: flags}
+	}
+}
+
+func (a *logger) Writer(level Level, options ...WriterOption) io.WriteCloser {
+	return a.root.Writer(level, options...)
+}
+
+// Write logs each complete line in p as a separate entry.
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, line := range w.lines.add(p) {
+		// The caller of Write is the callsite of the entry.
+		w.f.emit(w.level, "", []interface{}{w.text(line)}, false)
+	}
+	return len(p), nil
+}
+
+// Close logs any last line that does not end with a newline.
+func (w *logWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if rest := w.lines.rest(); len(rest) != 0 {
+		w.f.emit(w.level, "", []interface{}{w.text(rest)}, false)
+	}
+	return nil
+}
+
+// text returns the message of the entry for line.
+func (w *logWriter) text(line []byte) string {
+	if w.header != nil {
+		line = w.header.strip(line)
+	}
+	return string(line)
+}
+
+// nopWriteCloser is an io.WriteCloser with a Close method that does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// stdLogHeader is the header that a stdlib log.Logger writes before each
+// line, as determined by its prefix and flags.
+type stdLogHeader struct {
+	prefix []byte
+	flags  int
+}
+
+// strip returns line without the header, or line if it does not start with
+// the header.
+func (h *stdLogHeader) strip(line []byte) []byte {
+	rest := line
+	if h.flags&log.Lmsgprefix == 0 {
+		if !bytes.HasPrefix(rest, h.prefix) {
+			return line
+		}
+		rest = rest[len(h.prefix):]
+	}
+	if h.flags&log.Ldate != 0 {
+		// 2009/01/23
+		if !matchDigits(rest, "dddd/dd/dd ") {
+			return line
+		}
+		rest = rest[len("2009/01/23 "):]
+	}
+	if h.flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		layout := "dd:dd:dd "
+		if h.flags&log.Lmicroseconds != 0 {
+			layout = "dd:dd:dd.dddddd "
+		}
+		if !matchDigits(rest, layout) {
+			return line
+		}
+		rest = rest[len(layout):]
+	}
+	if h.flags&(log.Lshortfile|log.Llongfile) != 0 {
+		// file.go:23:
+		i := bytes.Index(rest, []byte(": "))
+		if i < 0 {
+			return line
+		}
+		rest = rest[i+2:]
+	}
+	if h.flags&log.Lmsgprefix != 0 {
+		if !bytes.HasPrefix(rest, h.prefix) {
+			return line
+		}
+		rest = rest[len(h.prefix):]
+	}
+	return rest
+}
+
+// matchDigits reports whether b starts with the layout, in which 'd' matches
+// any digit and other bytes match themselves.
+func matchDigits(b []byte, layout string) bool {
+	if len(b) < len(layout) {
+		return false
+	}
+	for i := 0; i < len(layout); i++ {
+		if layout[i] == 'd' {
+			if b[i] < '0' || b[i] > '9' {
+				return false
+			}
+		} else if b[i] != layout[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewStdLog creates a stdlib log.Logger that writes each line it outputs to
+// lg as an entry at the given level.  This is for use with libraries that
+// accept a *log.Logger.  The returned log.Logger has no prefix and no flags
+// set, since timestamps are provided by lg.
+func NewStdLog(lg Logger, level Level) *log.Logger {
+	return log.New(lg.Writer(level), "", 0)
+}
+
+// RedirectStdLog redirects the output of the stdlib log package's standard
+// logger to lg, logging each line as an entry at the given level.
+//
+// The standard logger's flags and prefix are cleared so that stdlib
+// timestamps and prefixes are stripped from the redirected output; alog
+// provides its own.  The returned function logs any last line that does not
+// end with a newline, and restores the standard logger's previous output,
+// flags, and prefix.
+func RedirectStdLog(lg Logger, level Level) func() {
+	oldOut := log.Writer()
+	oldFlags := log.Flags()
+	oldPrefix := log.Prefix()
+
+	log.SetFlags(0)
+	log.SetPrefix("")
+	w := lg.Writer(level)
+	log.SetOutput(w)
+
+	return func() {
+		log.SetOutput(oldOut)
+		w.Close()
+		log.SetFlags(oldFlags)
+		log.SetPrefix(oldPrefix)
+	}
+}
@@ -0,0 +1,96 @@
+package alog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+
+	w := lg.WithField("src", "lib").Writer(WarnLevel)
+	w.Write([]byte("first line\nsecond "))
+	w.Write([]byte("line\r\nincomplete"))
+	lg.Writer(DebugLevel).Write([]byte("filtered\n"))
+	w.Close()
+	w.Close()
+	lg.Close()
+
+	checkLines(t, buf,
+		" WARN first line (src=lib)",
+		" WARN second line (src=lib)",
+		" WARN incomplete (src=lib)")
+}
+
+func TestWriterLongLine(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+
+	w := lg.Writer(InfoLevel)
+	w.Write(bytes.Repeat([]byte{'x'}, maxLineSize+10))
+	w.Write([]byte("y\n"))
+	lg.Close()
+
+	lines := outputLines(buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if len(lines[0]) != len(" INFO ")+maxLineSize {
+		t.Error("wrong length of first line:", len(lines[0]))
+	}
+	if lines[1] != " INFO "+strings.Repeat("x", 10)+"y" {
+		t.Errorf("bad second line: %q", lines[1])
+	}
+}
+
+func TestStripStdLogHeader(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+
+	for _, flags := range []int{
+		0,
+		log.LstdFlags,
+		log.LstdFlags | log.Lmicroseconds | log.Lshortfile,
+		log.Ltime | log.Llongfile | log.Lmsgprefix,
+	} {
+		w := lg.Writer(InfoLevel, StripStdLogHeader("lib: ", flags))
+		log.New(w, "lib: ", flags).Print("hello")
+	}
+	// A line without the header is logged unchanged.
+	w := lg.Writer(InfoLevel, StripStdLogHeader("lib: ", log.LstdFlags))
+	w.Write([]byte("lib: no time\n"))
+	lg.Close()
+
+	expect := " INFO hello\n" +
+		" INFO hello\n" +
+		" INFO hello\n" +
+		" INFO hello\n" +
+		" INFO lib: no time\n"
+	if buf.String() != expect {
+		t.Errorf("bad output: %q", buf.String())
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	buf := new(bytes.Buffer)
+	lg := NewText(buf, InfoLevel, " ", "")
+
+	log.SetPrefix("stdlog: ")
+	restore := RedirectStdLog(lg, InfoLevel)
+	log.Print("hello from stdlib")
+	restore()
+	lg.Close()
+
+	if log.Prefix() != "stdlog: " {
+		t.Error("stdlib prefix not restored")
+	}
+	log.SetPrefix("")
+
+	s := buf.String()
+	if s != " INFO hello from stdlib\n" {
+		t.Errorf("bad redirected output: %q", s)
+	}
+}